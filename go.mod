@@ -1,5 +1,5 @@
 module github.com/jcdotter/gosimple
 
-go 1.18
+go 1.21
 
-require github.com/google/uuid v1.3.0 // indirect
+require github.com/google/uuid v1.6.0
@@ -0,0 +1,149 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Overflow manages the behavior of an async
+// log writer when its buffer is full
+type Overflow uint
+
+const (
+	Block      Overflow = iota // wait for room in the buffer
+	DropOldest                 // drop the oldest record in the buffer
+	DropNewest                 // drop the record being posted
+)
+
+// asyncWriter is an io.Writer which enqueues records
+// onto a bounded buffer which is drained to the
+// underlying writer by a background goroutine
+type asyncWriter struct {
+	w        io.Writer     // the underlying writer
+	queue    chan []byte   // the bounded buffer of records
+	overflow Overflow      // the behavior when the buffer is full
+	dropped  uint64        // the count of records dropped
+	mu       sync.Mutex    // guards pending and closed
+	cond     *sync.Cond    // signals when pending records are written
+	pending  int           // the count of records not yet written
+	closed   bool          // if true, records are no longer accepted
+	done     chan struct{} // closed when the background goroutine exits
+}
+
+// newAsyncWriter starts a background goroutine draining
+// a buffer of 'size' records to the writer 'w'
+func newAsyncWriter(w io.Writer, size int, o Overflow) *asyncWriter {
+	if size < 1 {
+		size = 1
+	}
+	a := &asyncWriter{
+		w:        w,
+		queue:    make(chan []byte, size),
+		overflow: o,
+		done:     make(chan struct{}),
+	}
+	a.cond = sync.NewCond(&a.mu)
+	go a.drain()
+	return a
+}
+
+// Write enqueues a copy of the record 'p' using the
+// overflow behavior of the writer if the buffer is full
+func (a *asyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		atomic.AddUint64(&a.dropped, 1)
+		return 0, io.ErrClosedPipe
+	}
+	a.pending++
+	a.mu.Unlock()
+	r := append([]byte(nil), p...)
+	switch a.overflow {
+	case DropNewest:
+		select {
+		case a.queue <- r:
+		default:
+			a.drop()
+		}
+	case DropOldest:
+		for {
+			select {
+			case a.queue <- r:
+				return len(p), nil
+			default:
+			}
+			select {
+			case <-a.queue:
+				a.drop()
+			default:
+			}
+		}
+	default:
+		a.queue <- r
+	}
+	return len(p), nil
+}
+
+// drain writes the records in the buffer to
+// the underlying writer until the buffer is closed
+func (a *asyncWriter) drain() {
+	defer close(a.done)
+	for r := range a.queue {
+		a.w.Write(r)
+		a.release()
+	}
+}
+
+// drop counts a record dropped from the buffer
+func (a *asyncWriter) drop() {
+	atomic.AddUint64(&a.dropped, 1)
+	a.release()
+}
+
+// release marks a pending record as written or dropped
+func (a *asyncWriter) release() {
+	a.mu.Lock()
+	a.pending--
+	if a.pending == 0 {
+		a.cond.Broadcast()
+	}
+	a.mu.Unlock()
+}
+
+// Flush waits until all records enqueued
+// are written to the underlying writer
+func (a *asyncWriter) Flush() {
+	a.mu.Lock()
+	for a.pending > 0 {
+		a.cond.Wait()
+	}
+	a.mu.Unlock()
+}
+
+// Close flushes the buffer and stops the background
+// goroutine. Records posted after Close are dropped
+func (a *asyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.mu.Unlock()
+	a.Flush()
+	close(a.queue)
+	<-a.done
+	return nil
+}
+
+// Dropped returns the count of records dropped
+func (a *asyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
@@ -0,0 +1,101 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// gateWriter is a writer which blocks
+// writes until its gate is opened
+type gateWriter struct {
+	gate chan struct{}
+	mu   sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gateWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncFlush(t *testing.T) {
+	w := &gateWriter{gate: make(chan struct{})}
+	close(w.gate)
+	lg := New(WithWriter(w), WithFormat(LogMessage), WithAsync(4, Block))
+	for _, m := range []string{"a", "b", "c", "d", "e", "f"} {
+		lg.Info(m)
+	}
+	lg.Flush()
+	if r := w.String(); r != "a\nb\nc\nd\ne\nf\n" {
+		t.Fatalf("async logger did not write all records in order on Flush: %q", r)
+	}
+	if lg.Dropped() != 0 {
+		t.Fatal("async logger dropped records while blocking")
+	}
+}
+
+func TestAsyncOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		o   Overflow
+		exp string
+	}{
+		{DropNewest, "a\nb\nc\n"},
+		{DropOldest, "a\nd\ne\n"},
+	} {
+		w := &gateWriter{gate: make(chan struct{})}
+		lg := New(WithWriter(w), WithFormat(LogMessage), WithAsync(2, tc.o))
+		lg.Info("a")
+		// wait for the drain to take 'a' and block on the gate
+		for len(lg.async.queue) > 0 {
+			runtime.Gosched()
+		}
+		for _, m := range []string{"b", "c", "d", "e"} {
+			lg.Info(m)
+		}
+		close(w.gate)
+		lg.Flush()
+		if r := w.String(); r != tc.exp {
+			t.Fatalf("async logger with overflow %d did not drop records: %q", tc.o, r)
+		}
+		if lg.Dropped() != 2 {
+			t.Fatalf("async logger with overflow %d did not count dropped records: %d", tc.o, lg.Dropped())
+		}
+	}
+}
+
+func TestAsyncClose(t *testing.T) {
+	dir := t.TempDir()
+	lg := New(WithDir(dir), WithFile("a.log"), WithConsole(false), WithFormat(LogMessage), WithAsync(64, Block))
+	for i := 0; i < 100; i++ {
+		lg.Info("record")
+	}
+	if err := lg.Close(); err != nil {
+		t.Fatal("could not close async logger:", err)
+	}
+	b, _ := os.ReadFile(filepath.Join(dir, "a.log"))
+	if n := strings.Count(string(b), "record\n"); n != 100 {
+		t.Fatalf("async logger did not drain records on Close: %d of 100", n)
+	}
+	lg.Info("late")
+	if lg.Dropped() != 1 {
+		t.Fatal("async logger did not drop record posted after Close")
+	}
+}
@@ -0,0 +1,671 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// configEnvPrefix is the prefix of the env vars of a
+// Config, such as GOSIMPLE_LOG_LEVEL for 'level'
+const configEnvPrefix = "GOSIMPLE_LOG_"
+
+// Config is the configuration of a Logger, which may be
+// loaded from a JSON or YAML file and the environment
+// with LoadConfig. The keys of the file are the json names
+// of the fields and the env vars their upper case names
+// prefixed by GOSIMPLE_LOG_, such as GOSIMPLE_LOG_TIME_FORMAT.
+// Lists are comma separated in env vars and durations
+// are formatted as in time.ParseDuration, such as "24h".
+// Empty fields keep the configs of the logger
+type Config struct {
+	Level            string   `json:"level"`             // the minimum level posted, such as "info"
+	Format           string   `json:"format"`            // the line format: std, json or logfmt
+	Elements         []string `json:"elements"`          // the elements of a record in order, such as level and message
+	Template         string   `json:"template"`          // the user-defined format of a line, overiding format
+	Delim            string   `json:"delim"`             // the delimiter between elements of the std format
+	TimeFormat       string   `json:"time_format"`       // the datetime format, as in the go time pkg
+	Console          *bool    `json:"console"`           // if false, records are not posted to console
+	ConsoleMode      string   `json:"console_mode"`      // the console format: auto, color, plain or raw
+	Dir              string   `json:"dir"`               // the directory of the log files, created if missing
+	File             string   `json:"file"`              // the name of the log file
+	Host             string   `json:"host"`              // the host posted in records
+	Service          string   `json:"service"`           // the service posted in records
+	Session          string   `json:"session"`           // the session posted in records
+	SessionID        string   `json:"session_id"`        // the session generator: time, uuidv4, uuidv7 or ulid
+	SessionHeader    bool     `json:"session_header"`    // if true, a header record is posted at session start
+	StackTrace       string   `json:"stack_trace"`       // the minimum level posted with a stack trace
+	Async            int      `json:"async"`             // the buffer size of async writes, 0 for sync writes
+	Overflow         string   `json:"overflow"`          // the async overflow: block, drop_oldest or drop_newest
+	RotateSize       int64    `json:"rotate_size"`       // the max size in bytes of a log file
+	RotateInterval   string   `json:"rotate_interval"`   // the cutover interval of log files
+	RotateBackups    int      `json:"rotate_backups"`    // the max count of rotated files retained
+	RotateAge        string   `json:"rotate_age"`        // the max age of rotated files retained
+	RotateCompress   bool     `json:"rotate_compress"`   // if true, rotated files are gzipped
+	SampleFirst      int      `json:"sample_first"`      // the records posted per call site per interval
+	SampleThereafter int      `json:"sample_thereafter"` // post every Mth record after sample_first
+	SampleInterval   string   `json:"sample_interval"`   // the sampling interval
+	SlowThreshold    string   `json:"slow_threshold"`    // the duration above which spans are posted as warnings
+}
+
+// ConfigError is the error of an invalid value of a Config
+type ConfigError struct {
+	Key   string // the json name of the config
+	Value string // the invalid value
+	Err   error  // the cause of the error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid log config %s %q: %v", e.Key, e.Value, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// format names of a Config
+var configFormats = map[string]int{"std": LogStdFmt, "json": LogJsonFmt, "logfmt": LogLogfmt}
+
+// console mode names of a Config
+var configConsoleModes = map[string]ConsoleMode{
+	"auto": ConsoleAuto, "color": ConsoleColor, "plain": ConsolePlain, "raw": ConsoleRaw,
+}
+
+// session generator names of a Config
+var configSessionGens = map[string]SessionGenerator{
+	"time": SessionTime, "uuidv4": SessionUUIDv4, "uuidv7": SessionUUIDv7, "ulid": SessionULID,
+}
+
+// overflow names of a Config
+var configOverflows = map[string]Overflow{"block": Block, "drop_oldest": DropOldest, "drop_newest": DropNewest}
+
+// ErrConfigActive is returned by Configure with the names
+// of the configs which cannot be applied to an active logger
+var ErrConfigActive = errors.New("log configs cannot be applied to an active logger")
+
+// yamlSubset describes the subset of YAML of the config files
+const yamlSubset = "log config files support a subset of YAML of top level key: value pairs, " +
+	"plain or quoted scalars, inline [a, b] lists and block lists of '- item' lines"
+
+// LoadConfig loads the Config of the JSON or YAML file
+// at 'path', by its .json, .yaml or .yml extension, and
+// overides its values with the GOSIMPLE_LOG_ env vars.
+// The config is loaded from env vars only if 'path' is empty.
+// Unknown keys and malformed values are returned as errors.
+// YAML files are parsed without external dependencies and
+// support a subset of YAML: top level key: value pairs of
+// plain, single or double quoted scalars, inline [a, b]
+// lists and block lists of '- item' lines, and comments.
+// Nested mappings, flow mappings, block scalars, anchors
+// and aliases are returned as errors
+func LoadConfig(path string) (Config, error) {
+	c := Config{}
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return c, err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			d := json.NewDecoder(bytes.NewReader(b))
+			d.DisallowUnknownFields()
+			err = d.Decode(&c)
+		case ".yaml", ".yml":
+			err = parseYAMLConfig(b, &c)
+		default:
+			err = errors.New("unknown config file extension")
+		}
+		if err != nil {
+			return c, fmt.Errorf("could not load log config %s: %w", path, err)
+		}
+	}
+	var errs []error
+	t := reflect.TypeOf(c)
+	for i := 0; i < t.NumField(); i++ {
+		k := t.Field(i).Tag.Get("json")
+		if v, ok := os.LookupEnv(configEnvPrefix + strings.ToUpper(k)); ok {
+			if err := c.set(k, v); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return c, errors.Join(errs...)
+}
+
+// set sets the config of json name 'k' to
+// the value 'v', parsed by the type of the config
+func (c *Config) set(k, v string) error {
+	rv := reflect.ValueOf(c).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		if rv.Type().Field(i).Tag.Get("json") != k {
+			continue
+		}
+		f := rv.Field(i)
+		switch f.Kind() {
+		case reflect.String:
+			f.SetString(v)
+		case reflect.Slice:
+			var l []string
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					l = append(l, s)
+				}
+			}
+			f.Set(reflect.ValueOf(l))
+		case reflect.Bool, reflect.Pointer:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return &ConfigError{k, v, errors.New("not a boolean")}
+			}
+			if f.Kind() == reflect.Bool {
+				f.SetBool(b)
+			} else {
+				f.Set(reflect.ValueOf(&b))
+			}
+		case reflect.Int, reflect.Int64:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return &ConfigError{k, v, errors.New("not an integer")}
+			}
+			f.SetInt(n)
+		}
+		return nil
+	}
+	return &ConfigError{k, v, errors.New("unknown config")}
+}
+
+// parseYAMLConfig parses the YAML document 'b' of
+// key: value pairs to the config 'c'. Lists may be
+// inline, such as [level, message], or a block of
+// '- item' lines. Values outside of the subset of
+// YAML in yamlSubset are returned as errors
+func parseYAMLConfig(b []byte, c *Config) error {
+	var errs []error
+	var list string    // the key of the block list being parsed, if any
+	var items []string // the items of the block list
+	end := func() {
+		if list != "" {
+			if err := c.set(list, strings.Join(items, ",")); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		list, items = "", nil
+	}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; s.Scan(); n++ {
+		ln := strings.TrimRight(yamlComment(s.Text()), " \t")
+		t := strings.TrimSpace(ln)
+		switch {
+		case t == "" || t == "---":
+			continue
+		case strings.HasPrefix(t, "- ") || t == "-":
+			if list == "" {
+				errs = append(errs, fmt.Errorf("line %d: list item without key", n))
+				continue
+			}
+			items = append(items, yamlScalar(strings.TrimSpace(t[1:])))
+			continue
+		}
+		end()
+		if ln != t {
+			errs = append(errs, fmt.Errorf("line %d: nested values are not supported: %s", n, yamlSubset))
+			continue
+		}
+		k, v, ok := strings.Cut(t, ":")
+		if !ok {
+			errs = append(errs, fmt.Errorf("line %d: expected key: value: %s", n, yamlSubset))
+			continue
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		switch {
+		case v != "" && strings.ContainsRune("|>{&*!", rune(v[0])):
+			errs = append(errs, fmt.Errorf("line %d: unsupported value %q: %s", n, v, yamlSubset))
+		case v == "":
+			list = k
+		case strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]"):
+			var l []string
+			for _, i := range strings.Split(v[1:len(v)-1], ",") {
+				l = append(l, yamlScalar(strings.TrimSpace(i)))
+			}
+			v = strings.Join(l, ",")
+			fallthrough
+		default:
+			if err := c.set(k, yamlScalar(v)); err != nil {
+				errs = append(errs, fmt.Errorf("line %d: %w", n, err))
+			}
+		}
+	}
+	end()
+	return errors.Join(errs...)
+}
+
+// yamlComment removes the comment of the
+// YAML line 'ln' outside of quoted values
+func yamlComment(ln string) string {
+	var q byte
+	for i := 0; i < len(ln); i++ {
+		switch c := ln[i]; {
+		case q != 0:
+			if c == '\\' && q == '"' {
+				i++
+			} else if c == q {
+				q = 0
+			}
+		case c == '"' || c == '\'':
+			q = c
+		case c == '#' && (i == 0 || ln[i-1] == ' ' || ln[i-1] == '\t'):
+			return ln[:i]
+		}
+	}
+	return ln
+}
+
+// yamlScalar returns the value of the YAML scalar
+// 's', unquoting double and single quoted values
+func yamlScalar(s string) string {
+	switch {
+	case len(s) > 1 && s[0] == '"' && s[len(s)-1] == '"':
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	case len(s) > 1 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
+
+// Validate returns the errors of the
+// invalid values of the config, if any
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(k, v string, err string) {
+		errs = append(errs, &ConfigError{k, v, errors.New(err)})
+	}
+	for k, v := range map[string]string{"level": c.Level, "stack_trace": c.StackTrace} {
+		if _, ok := levelByName(v); v != "" && !ok {
+			invalid(k, v, "unknown level")
+		}
+	}
+	if _, ok := configFormats[strings.ToLower(c.Format)]; c.Format != "" && !ok {
+		invalid("format", c.Format, "expected std, json or logfmt")
+	}
+	for _, e := range c.Elements {
+		if !isElName(strings.ToLower(e)) {
+			invalid("elements", e, "unknown element")
+		}
+	}
+	if c.Template != "" && len(newTemplate(c.Template).els) == 0 {
+		invalid("template", c.Template, "template has no placeholders")
+	}
+	if c.TimeFormat != "" {
+		if _, err := time.Parse(c.TimeFormat, c.TimeFormat); err != nil {
+			invalid("time_format", c.TimeFormat, "invalid datetime format")
+		}
+	}
+	if _, ok := configConsoleModes[strings.ToLower(c.ConsoleMode)]; c.ConsoleMode != "" && !ok {
+		invalid("console_mode", c.ConsoleMode, "expected auto, color, plain or raw")
+	}
+	if _, ok := configSessionGens[strings.ToLower(c.SessionID)]; c.SessionID != "" && !ok {
+		invalid("session_id", c.SessionID, "expected time, uuidv4, uuidv7 or ulid")
+	}
+	if c.Dir != "" {
+		if info, err := os.Stat(c.Dir); err == nil && !info.IsDir() {
+			invalid("dir", c.Dir, "not a directory")
+		}
+	}
+	if c.Async < 0 {
+		invalid("async", strconv.Itoa(c.Async), "negative buffer size")
+	}
+	if _, ok := configOverflows[strings.ToLower(c.Overflow)]; c.Overflow != "" && !ok {
+		invalid("overflow", c.Overflow, "expected block, drop_oldest or drop_newest")
+	}
+	for k, v := range map[string]int64{
+		"rotate_size":       c.RotateSize,
+		"rotate_backups":    int64(c.RotateBackups),
+		"sample_first":      int64(c.SampleFirst),
+		"sample_thereafter": int64(c.SampleThereafter),
+	} {
+		if v < 0 {
+			invalid(k, strconv.FormatInt(v, 10), "negative value")
+		}
+	}
+	for k, v := range map[string]string{
+		"rotate_interval": c.RotateInterval,
+		"rotate_age":      c.RotateAge,
+		"sample_interval": c.SampleInterval,
+		"slow_threshold":  c.SlowThreshold,
+	} {
+		if d, err := time.ParseDuration(v); v != "" && err != nil {
+			invalid(k, v, "invalid duration")
+		} else if d < 0 {
+			invalid(k, v, "negative duration")
+		}
+	}
+	sortConfigErrors(errs)
+	return errors.Join(errs...)
+}
+
+// sortConfigErrors sorts the errors 'errs'
+// of a config by the order of the config fields
+func sortConfigErrors(errs []error) {
+	t := reflect.TypeOf(Config{})
+	pos := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		pos[t.Field(i).Tag.Get("json")] = i
+	}
+	key := func(e error) int {
+		var ce *ConfigError
+		errors.As(e, &ce)
+		return pos[ce.Key]
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return key(errs[i]) < key(errs[j]) })
+}
+
+// static returns the configs of 'c' which cannot
+// be applied to an active logger, with names in lower
+// case, durations and paths in canonical form
+func (c Config) static() Config {
+	c.Level, c.StackTrace, c.SlowThreshold = "", "", ""
+	c.Format, c.ConsoleMode = strings.ToLower(c.Format), strings.ToLower(c.ConsoleMode)
+	c.SessionID, c.Overflow = strings.ToLower(c.SessionID), strings.ToLower(c.Overflow)
+	c.Elements = append([]string(nil), c.Elements...)
+	for i, e := range c.Elements {
+		c.Elements[i] = strings.ToLower(e)
+	}
+	for _, d := range []*string{&c.RotateInterval, &c.RotateAge, &c.SampleInterval} {
+		if v, err := time.ParseDuration(*d); err == nil {
+			*d = configDuration(v)
+		}
+	}
+	if c.Dir != "" {
+		c.Dir = configPath(c.Dir)
+	}
+	return c
+}
+
+// settings returns the configs of the logger
+// which cannot be applied once it is active
+func (lg *Logger) settings() Config {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	c := Config{
+		Format:        "std",
+		Delim:         lg.delim,
+		TimeFormat:    lg.timeFmt,
+		Console:       &lg.toConsole,
+		File:          lg.file,
+		Host:          lg.host,
+		Service:       lg.service,
+		Session:       lg.session,
+		SessionID:     "time",
+		SessionHeader: lg.header,
+		Async:         lg.asyncSize,
+	}
+	switch {
+	case lg.jsonFmt:
+		c.Format = "json"
+	case lg.logfmt:
+		c.Format = "logfmt"
+	}
+	for _, i := range lg.format {
+		c.Elements = append(c.Elements, elNames[i])
+	}
+	if lg.tmpl != nil {
+		c.Template = lg.tmpl.text
+	}
+	if lg.dir != "" {
+		c.Dir = configPath(lg.dir)
+	}
+	for k, m := range configConsoleModes {
+		if m == lg.consoleMode {
+			c.ConsoleMode = k
+		}
+	}
+	for k, g := range configSessionGens {
+		if lg.sessionGen != nil && reflect.ValueOf(g).Pointer() == reflect.ValueOf(lg.sessionGen).Pointer() {
+			c.SessionID = k
+		}
+	}
+	for k, o := range configOverflows {
+		if o == lg.overflow {
+			c.Overflow = k
+		}
+	}
+	if r := lg.rotation; r != nil {
+		c.RotateSize, c.RotateBackups, c.RotateCompress = r.MaxSize, r.MaxBackups, r.Compress
+		c.RotateInterval, c.RotateAge = configDuration(r.Interval), configDuration(r.MaxAge)
+	}
+	if lg.limiter != nil {
+		s := lg.limiter.sample
+		c.SampleFirst, c.SampleThereafter, c.SampleInterval = s.First, s.Thereafter, configDuration(s.Interval)
+	}
+	return c
+}
+
+// configDuration formats the duration 'd' of a
+// config, or returns an empty string if 'd' is zero
+func configDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// configPath returns the absolute path of
+// the path 'p' of a config, if it can be resolved
+func configPath(p string) string {
+	if a, err := filepath.Abs(p); err == nil {
+		return a
+	}
+	return filepath.Clean(p)
+}
+
+// diff returns the json names of the non-empty
+// configs of 'c' whose values differ from those of 'o'
+func (c Config) diff(o Config) []string {
+	var ks []string
+	cv, ov := reflect.ValueOf(c), reflect.ValueOf(o)
+	for i := 0; i < cv.NumField(); i++ {
+		if !cv.Field(i).IsZero() && !reflect.DeepEqual(cv.Field(i).Interface(), ov.Field(i).Interface()) {
+			ks = append(ks, cv.Type().Field(i).Tag.Get("json"))
+		}
+	}
+	return ks
+}
+
+// Configure validates the config 'c' and applies its
+// non-empty values to the logger, creating the log
+// directory if it does not exist. No values are applied
+// if the config is invalid. Once the logger is active,
+// only the level, stack trace level and slow threshold
+// may be changed, and no values are applied if the
+// config changes others, which are returned as an
+// error wrapping ErrConfigActive
+func (lg *Logger) Configure(c Config) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	if lg.isActive() {
+		if ks := c.static().diff(lg.settings()); len(ks) > 0 {
+			return fmt.Errorf("%w: %s", ErrConfigActive, strings.Join(ks, ", "))
+		}
+	}
+	if c.Dir != "" {
+		if err := os.MkdirAll(c.Dir, os.ModePerm); err != nil {
+			return &ConfigError{"dir", c.Dir, err}
+		}
+	}
+	if c.Level != "" {
+		l, _ := levelByName(c.Level)
+		lg.SetLevel(l)
+	}
+	if c.StackTrace != "" {
+		l, _ := levelByName(c.StackTrace)
+		lg.SetStackTrace(l)
+	}
+	if c.SlowThreshold != "" {
+		d, _ := time.ParseDuration(c.SlowThreshold)
+		lg.SetSlowThreshold(d)
+	}
+	if lg.isActive() {
+		return nil
+	}
+	if c.Dir != "" {
+		lg.SetDir(c.Dir)
+	}
+	var f []int
+	if c.Format != "" {
+		f = append(f, configFormats[strings.ToLower(c.Format)])
+	}
+	for _, e := range c.Elements {
+		if el, ok := ElementByName(e); ok {
+			f = append(f, el)
+		}
+	}
+	lg.SetFormat(f...)
+	if c.Template != "" {
+		lg.SetTemplate(c.Template)
+	}
+	if c.Delim != "" {
+		lg.SetDelim(c.Delim)
+	}
+	if c.TimeFormat != "" {
+		lg.SetDateTimeFormat(c.TimeFormat)
+	}
+	if c.Console != nil {
+		lg.LogToConsole(*c.Console)
+	}
+	if c.ConsoleMode != "" {
+		lg.SetConsoleMode(configConsoleModes[strings.ToLower(c.ConsoleMode)])
+	}
+	for _, s := range []struct {
+		v   string
+		set func(string)
+	}{
+		{c.File, lg.SetFile},
+		{c.Host, lg.SetHost},
+		{c.Service, lg.SetService},
+		{c.Session, lg.SetSession},
+	} {
+		if s.v != "" {
+			s.set(s.v)
+		}
+	}
+	if c.SessionID != "" {
+		lg.SetSessionGenerator(configSessionGens[strings.ToLower(c.SessionID)])
+	}
+	if c.SessionHeader {
+		lg.SetSessionHeader("")
+	}
+	if c.Async > 0 {
+		lg.SetAsync(c.Async, configOverflows[strings.ToLower(c.Overflow)])
+	}
+	if c.RotateSize > 0 || c.RotateInterval != "" || c.RotateBackups > 0 || c.RotateAge != "" || c.RotateCompress {
+		r := Rotation{MaxSize: c.RotateSize, MaxBackups: c.RotateBackups, Compress: c.RotateCompress}
+		r.Interval, _ = time.ParseDuration(c.RotateInterval)
+		r.MaxAge, _ = time.ParseDuration(c.RotateAge)
+		lg.SetRotation(r)
+	}
+	if c.SampleFirst > 0 {
+		s := Sampling{First: c.SampleFirst, Thereafter: c.SampleThereafter}
+		s.Interval, _ = time.ParseDuration(c.SampleInterval)
+		lg.SetSampling(s)
+	}
+	return nil
+}
+
+// Configure validates the config 'c' and
+// applies its values to the default logger
+func Configure(c Config) error {
+	return Default().Configure(c)
+}
+
+// WatchConfig configures the logger with the config
+// loaded from the file at 'path' and the env vars, and
+// reloads the config when the file is modified, checking
+// the file every 'interval'. Configs which could not be
+// reloaded are posted to the logger as WARNING records.
+// The file is reloaded once its size and modification
+// time are unchanged for an interval, so that it is not
+// read while written. Configs are reloaded in the manner
+// of Configure, and only the level, stack trace level and
+// slow threshold may be changed once the logger is active.
+// The file is watched until 'stop' is called
+func (lg *Logger) WatchConfig(path string, interval time.Duration) (stop func(), err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := LoadConfig(path)
+	if err == nil {
+		err = lg.Configure(c)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the size and modification time of the file loaded
+	type stamp struct {
+		mod  time.Time
+		size int64
+	}
+	stampOf := func(info os.FileInfo) stamp { return stamp{info.ModTime(), info.Size()} }
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		loaded, seen := stampOf(info), stampOf(info)
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			// the file is reloaded once unchanged across two checks
+			s := stampOf(info)
+			if s.mod.Equal(loaded.mod) && s.size == loaded.size {
+				seen = s
+				continue
+			}
+			if !s.mod.Equal(seen.mod) || s.size != seen.size {
+				seen = s
+				continue
+			}
+			loaded = s
+			c, err := LoadConfig(path)
+			if err == nil {
+				err = lg.Configure(c)
+			}
+			if err != nil {
+				lg.Warning("could not reload log config", "path", path, Err(err))
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }, nil
+}
+
+// WatchConfig configures the default logger with the
+// config of the file at 'path' and the env vars, and
+// reloads the config when the file is modified
+func WatchConfig(path string, interval time.Duration) (stop func(), err error) {
+	return Default().WatchConfig(path, interval)
+}
@@ -0,0 +1,218 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, s string) string {
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(s), os.ModePerm); err != nil {
+		t.Fatal("could not write config file:", err)
+	}
+	return p
+}
+
+// replaceConfig replaces the config file at 'p' with
+// the config 's' modified at 'mod' in a single rename
+func replaceConfig(t *testing.T, p, s string, mod time.Time) {
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, []byte(s), os.ModePerm); err != nil {
+		t.Fatal("could not write config file:", err)
+	}
+	os.Chtimes(tmp, mod, mod)
+	if err := os.Rename(tmp, p); err != nil {
+		t.Fatal("could not replace config file:", err)
+	}
+}
+
+// syncBuffer is a buffer which is
+// safe for concurrent use
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestLoadConfig(t *testing.T) {
+	no := false
+	exp := Config{
+		Level:       "warning",
+		Format:      "std",
+		Elements:    []string{"level", "session", "message"},
+		Delim:       " | ",
+		Console:     &no,
+		Service:     "api # v2",
+		RotateSize:  1024,
+		RotateAge:   "72h",
+		SampleFirst: 10,
+	}
+	yml := writeConfig(t, "log.yaml", `# log config
+level: warning
+format: std
+elements:
+  - level
+  - session
+  - message
+delim: " | "
+console: false
+service: 'api # v2' # quoted
+rotate_size: 1024
+rotate_age: 72h
+sample_first: 10
+`)
+	if c, err := LoadConfig(yml); err != nil || !reflect.DeepEqual(c, exp) {
+		t.Fatalf("LoadConfig did not load yaml config: %+v %v", c, err)
+	}
+	js := writeConfig(t, "log.json", `{"level":"warning","format":"std","elements":["level","session","message"],
+		"delim":" | ","console":false,"service":"api # v2","rotate_size":1024,"rotate_age":"72h","sample_first":10}`)
+	if c, err := LoadConfig(js); err != nil || !reflect.DeepEqual(c, exp) {
+		t.Fatalf("LoadConfig did not load json config: %+v %v", c, err)
+	}
+	t.Setenv("GOSIMPLE_LOG_LEVEL", "error")
+	t.Setenv("GOSIMPLE_LOG_ELEMENTS", "level, message")
+	t.Setenv("GOSIMPLE_LOG_CONSOLE", "true")
+	c, err := LoadConfig(yml)
+	if err != nil || c.Level != "error" || len(c.Elements) != 2 || !*c.Console || c.Format != "std" {
+		t.Fatalf("LoadConfig did not overide config with env vars: %+v %v", c, err)
+	}
+	if c, err := LoadConfig(""); err != nil || c.Level != "error" || c.Format != "" {
+		t.Fatalf("LoadConfig did not load config from env vars: %+v %v", c, err)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	t.Setenv("GOSIMPLE_LOG_ASYNC", "many")
+	_, err := LoadConfig(writeConfig(t, "log.yml", "level: info\ncolour: red\nrotation:\n  size: 10\n"))
+	for _, e := range []string{`line 2: invalid log config colour "red": unknown config`, "line 4: nested values are not supported: log config files support a subset of YAML"} {
+		if err == nil || !strings.Contains(err.Error(), e) {
+			t.Fatalf("LoadConfig did not return error of yaml config: %v", err)
+		}
+	}
+	for _, v := range []string{"|", "{dir: logs}", "&dir logs", "*dir"} {
+		_, err := LoadConfig(writeConfig(t, "log.yaml", "dir: "+v+"\n"))
+		if err == nil || !strings.Contains(err.Error(), "line 1: unsupported value") {
+			t.Fatalf("LoadConfig did not return error of unsupported yaml %q: %v", v, err)
+		}
+	}
+	if _, err := LoadConfig(writeConfig(t, "log.json", `{"colour":"red"}`)); err == nil {
+		t.Fatal("LoadConfig did not return error of unknown json key")
+	}
+	_, err = LoadConfig("")
+	var ce *ConfigError
+	if !errors.As(err, &ce) || ce.Key != "async" || ce.Value != "many" {
+		t.Fatalf("LoadConfig did not return error of env var: %v", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	err := Config{
+		Level:       "loud",
+		Elements:    []string{"level", "colour"},
+		TimeFormat:  "2006-13-45",
+		ConsoleMode: "rainbow",
+		Async:       -1,
+		RotateAge:   "a week",
+	}.Validate()
+	exp := `invalid log config level "loud": unknown level
+invalid log config elements "colour": unknown element
+invalid log config time_format "2006-13-45": invalid datetime format
+invalid log config console_mode "rainbow": expected auto, color, plain or raw
+invalid log config async "-1": negative buffer size
+invalid log config rotate_age "a week": invalid duration`
+	if err == nil || err.Error() != exp {
+		t.Fatalf("Validate did not return errors of invalid configs: %v", err)
+	}
+	b := new(bytes.Buffer)
+	lg := New(WithWriter(b), WithFormat(LogMessage))
+	if lg.Configure(Config{Level: "error", Format: "yaml"}) == nil || !lg.Enabled(INFO) {
+		t.Fatal("Configure applied invalid config")
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	b := new(bytes.Buffer)
+	dir := filepath.Join(t.TempDir(), "logs")
+	lg := New(WithWriter(b))
+	err := lg.Configure(Config{
+		Level:    "info",
+		Format:   "logfmt",
+		Elements: []string{"level", "session", "message"},
+		Session:  "s1",
+		Dir:      dir,
+	})
+	if err != nil {
+		t.Fatal("could not configure logger:", err)
+	}
+	lg.Trace("hidden")
+	lg.Info("shown", "k", "v")
+	if r := b.String(); r != "level=INFO session=s1 message=shown k=v\n" {
+		t.Fatalf("Configure did not apply config to logger: %q", r)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() || lg.dir != dir {
+		t.Fatal("Configure did not create log dir")
+	}
+	err = lg.Configure(Config{Level: "error", Format: "json", Host: "h1"})
+	if !errors.Is(err, ErrConfigActive) || !strings.HasSuffix(err.Error(), ": format, host") || !lg.Enabled(INFO) {
+		t.Fatalf("Configure did not return error of configs of active logger: %v", err)
+	}
+	err = lg.Configure(Config{Level: "error", Format: "LOGFMT", Elements: []string{"level", "session", "message"}, Session: "s1", Dir: dir})
+	if err != nil {
+		t.Fatal("could not configure active logger with its configs:", err)
+	}
+	b.Reset()
+	lg.Warning("hidden")
+	lg.Error("shown")
+	if r := b.String(); r != "level=ERROR session=s1 message=shown\n" {
+		t.Fatalf("Configure did not apply only level to active logger: %q", r)
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	b := &syncBuffer{}
+	p := writeConfig(t, "log.yaml", "level: warning\nelements: [level, message]\n")
+	lg := New(WithWriter(b))
+	stop, err := lg.WatchConfig(p, 5*time.Millisecond)
+	if err != nil {
+		t.Fatal("could not watch config:", err)
+	}
+	defer stop()
+	lg.Info("hidden")
+	replaceConfig(t, p, "level: info\nelements: [level, message]\n", time.Now().Add(time.Second))
+	for i := 0; i < 500 && !lg.Enabled(INFO); i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	lg.Info("shown")
+	replaceConfig(t, p, "level: error\nformat: json\nelements: [level, message]\n", time.Now().Add(2*time.Second))
+	for i := 0; i < 500 && !strings.Contains(b.String(), "WARNING"); i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	stop()
+	exp := "INFO \tshown\nWARNING \tcould not reload log config"
+	if r := b.String(); !strings.HasPrefix(r, exp) || !strings.Contains(r, `cannot be applied to an active logger: format`) || !lg.Enabled(INFO) {
+		t.Fatalf("WatchConfig did not reload config: %q", r)
+	}
+}
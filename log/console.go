@@ -0,0 +1,171 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConsoleMode manages the format of
+// the records posted to the console
+type ConsoleMode uint
+
+const (
+	ConsoleAuto  ConsoleMode = iota // human-friendly, colored if the console is a terminal and NO_COLOR is unset
+	ConsoleColor                    // human-friendly and colored
+	ConsolePlain                    // human-friendly without color
+	ConsoleRaw                      // the format of the log file
+)
+
+// ANSI escape codes of the console colors
+const (
+	colorReset = "\x1b[0m"
+	colorDim   = "\x1b[2m"
+	colorKey   = "\x1b[36m"
+)
+
+// levelColors are the ANSI colors of the levels
+var levelColors = []string{
+	TRACE:   "\x1b[90m",
+	INFO:    "\x1b[32m",
+	WARNING: "\x1b[33m",
+	ERROR:   "\x1b[31m",
+	FATAL:   "\x1b[1;35m",
+}
+
+// sourceWidth is the minimum width
+// of the source column of the console
+const sourceWidth = 20
+
+// ConsoleHandler is a Handler which writes records
+// to a console in a human-friendly format of aligned
+// columns of the time since the handler was created,
+// the level, source and message of the record
+// followed by its key=value fields
+type ConsoleHandler struct {
+	mu    sync.Mutex
+	w     io.Writer // the console writer
+	color bool      // if true, levels and keys are colored
+	level Level     // the minimum level written by the handler
+	start time.Time // the time the handler was created
+}
+
+// NewConsoleHandler creates a handler writing records
+// to the console 'w' in a human-friendly format using
+// the ConsoleMode 'm'. In ConsoleAuto mode, records are
+// colored if 'w' is a terminal and NO_COLOR is unset
+func NewConsoleHandler(w io.Writer, m ConsoleMode) *ConsoleHandler {
+	c := m == ConsoleColor
+	if m == ConsoleAuto {
+		c = isTerminal(w) && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	}
+	return &ConsoleHandler{w: w, color: c, start: time.Now()}
+}
+
+// Enabled evaluates whether records of
+// Level 'l' are written by the handler
+func (h *ConsoleHandler) Enabled(l Level) bool {
+	return l >= h.level
+}
+
+// Handle formats the record 'r' and
+// writes it to the console of the handler
+func (h *ConsoleHandler) Handle(r *Record) error {
+	b := h.format(r)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(b)
+	return err
+}
+
+// format formats the record 'r' to a console line
+// followed by the lines of its stack trace, if any
+func (h *ConsoleHandler) format(r *Record) []byte {
+	b := &strings.Builder{}
+	d := r.Time.Sub(h.start)
+	if d < 0 {
+		d = 0
+	}
+	h.paint(b, colorDim, fmt.Sprintf("+%9.3fs", d.Seconds()))
+	b.WriteByte(' ')
+	h.paint(b, levelColors[r.Level], fmt.Sprintf("%-7s", levelNames[r.Level]))
+	b.WriteByte(' ')
+	src := r.Source[strings.LastIndex(r.Source, "/")+1:]
+	h.paint(b, colorDim, fmt.Sprintf("%-*s", sourceWidth, src))
+	b.WriteByte(' ')
+	b.WriteString(strings.ReplaceAll(r.Message, "\n", "\n\t"))
+	var fs []Field
+	if r.TraceID != "" {
+		fs = append(fs, Field{"trace", r.TraceID})
+	}
+	if r.SpanID != "" {
+		fs = append(fs, Field{"span", r.SpanID})
+	}
+	fs = append(fs, r.Fields...)
+	for _, f := range fs {
+		b.WriteString("  ")
+		h.paint(b, colorKey, f.Key+"=")
+		b.WriteString(f.stdValue(defaultEncoder.timeFmt, ""))
+	}
+	for _, s := range r.Stack {
+		b.WriteString("\n\t")
+		h.paint(b, colorDim, s)
+	}
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+// paint writes the text 's' to 'b'
+// in the ANSI color 'c' if colored
+func (h *ConsoleHandler) paint(b *strings.Builder, c, s string) {
+	if !h.color {
+		b.WriteString(s)
+		return
+	}
+	b.WriteString(c)
+	b.WriteString(s)
+	b.WriteString(colorReset)
+}
+
+// isTerminal evaluates whether the writer 'w'
+// is a file connected to a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// WithConsoleMode sets the format of the
+// records posted to the console by a new Logger
+func WithConsoleMode(m ConsoleMode) Option {
+	return func(lg *Logger) { lg.SetConsoleMode(m) }
+}
+
+// SetConsoleMode sets the format of the records
+// posted to the console if LogToConsole(true).
+// Records are posted to the log file in the log
+// format regardless of the console mode
+func (lg *Logger) SetConsoleMode(m ConsoleMode) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.consoleMode = m
+	}
+}
+
+// SetConsoleMode sets the format of the
+// records posted to the console by the default logger
+func SetConsoleMode(m ConsoleMode) {
+	Default().SetConsoleMode(m)
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsoleHandler(t *testing.T) {
+	b := new(bytes.Buffer)
+	h := NewConsoleHandler(b, ConsolePlain)
+	r := &Record{
+		Level:   WARNING,
+		Time:    h.start.Add(1500 * time.Millisecond),
+		Source:  "/src/app/main.go:12",
+		Message: "slow request",
+		Fields:  []Field{F("path", "/a b"), F("took", 2*time.Second)},
+	}
+	h.Handle(r)
+	if s := b.String(); s != "+    1.500s WARNING main.go:12           slow request  path=\"/a b\"  took=2s\n" {
+		t.Fatalf("console handler did not write aligned columns: %q", s)
+	}
+	b.Reset()
+	h = NewConsoleHandler(b, ConsoleColor)
+	r.Time, r.Level, r.Stack = h.start, ERROR, []string{"main.main /src/app/main.go:12"}
+	h.Handle(r)
+	if s := b.String(); !strings.Contains(s, "\x1b[31mERROR  \x1b[0m") || !strings.Contains(s, "\x1b[36mpath=\x1b[0m") ||
+		!strings.HasSuffix(s, "\n\t\x1b[2mmain.main /src/app/main.go:12\x1b[0m\n") {
+		t.Fatalf("console handler did not write colored record: %q", s)
+	}
+	b.Reset()
+	h = NewConsoleHandler(b, ConsolePlain)
+	h.Handle(&Record{Level: INFO, Time: h.start, Message: "traced", TraceID: "t1"})
+	if s := b.String(); !strings.HasSuffix(s, "traced  trace=t1\n") {
+		t.Fatalf("console handler did not omit empty span: %q", s)
+	}
+	if NewConsoleHandler(b, ConsoleAuto).color {
+		t.Fatal("console handler colored records written to a buffer")
+	}
+}
+
+func TestConsoleMode(t *testing.T) {
+	dir, console := t.TempDir(), new(bytes.Buffer)
+	lg := New(WithDir(dir), WithFile("s.log"), WithFormat(LogLevel, LogMessage), WithDelim("|"))
+	lg.console = console
+	// the source of the record is the line after the caller
+	_, _, line, _ := runtime.Caller(0)
+	lg.Info("started", "port", 80)
+	lg.Close()
+	if b, _ := os.ReadFile(filepath.Join(dir, "s.log")); string(b) != "INFO|started|port=80\n" {
+		t.Fatalf("log file not written in log format: %q", b)
+	}
+	src := fmt.Sprintf("%-*s", sourceWidth, fmt.Sprintf("console_test.go:%d", line+1))
+	if s := console.String(); !strings.HasSuffix(s, " INFO    "+src+" started  port=80\n") {
+		t.Fatalf("console not written in console format: %q", s)
+	}
+}
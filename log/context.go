@@ -0,0 +1,168 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// ctxKey is the type of the keys
+// of the log values stored in a context
+type ctxKey int
+
+const (
+	loggerKey ctxKey = iota // the key of the logger in a context
+	traceKey                // the key of the trace id in a context
+	spanKey                 // the key of the span id in a context
+)
+
+// NewContext returns a copy of the context 'ctx'
+// carrying the logger 'lg' and its bound fields
+func NewContext(ctx context.Context, lg *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, lg)
+}
+
+// FromContext returns the logger carried by the
+// context 'ctx' or the default logger if the
+// context does not carry a logger
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if lg, ok := ctx.Value(loggerKey).(*Logger); ok {
+			return lg
+		}
+	}
+	return Default()
+}
+
+// ContextWithTrace returns a copy of the context 'ctx'
+// carrying the trace and span ids provided, which are
+// posted to the log by the context log functions at
+// LogTraceID and LogSpanID in the format, if any, or
+// else after the session of the record
+func ContextWithTrace(ctx context.Context, trace, span string) context.Context {
+	if trace != "" {
+		ctx = context.WithValue(ctx, traceKey, trace)
+	}
+	if span != "" {
+		ctx = context.WithValue(ctx, spanKey, span)
+	}
+	return ctx
+}
+
+// TraceFromContext returns the trace and span ids
+// carried by the context 'ctx' if any
+func TraceFromContext(ctx context.Context) (trace, span string) {
+	if ctx == nil {
+		return
+	}
+	trace, _ = ctx.Value(traceKey).(string)
+	span, _ = ctx.Value(spanKey).(string)
+	return
+}
+
+// LogCtx records an entry to the log file
+// and prints to console if LogToConsole(true)
+// using the Level 'l', 'msg' message provided,
+// the key/value fields in 'kv' and
+// the trace and span ids of the context 'ctx'
+func (lg *Logger) LogCtx(ctx context.Context, l Level, msg string, kv ...any) {
+	lg.outputCtx(ctx, 2, l, msg, kv...)
+}
+
+// TraceCtx records a TRACE entry to the log file
+// with the trace and span ids of the context 'ctx'
+func (lg *Logger) TraceCtx(ctx context.Context, msg string, kv ...any) {
+	lg.outputCtx(ctx, 2, TRACE, msg, kv...)
+}
+
+// InfoCtx records an INFO entry to the log file
+// with the trace and span ids of the context 'ctx'
+func (lg *Logger) InfoCtx(ctx context.Context, msg string, kv ...any) {
+	lg.outputCtx(ctx, 2, INFO, msg, kv...)
+}
+
+// WarningCtx records a WARNING entry to the log file
+// with the trace and span ids of the context 'ctx'
+func (lg *Logger) WarningCtx(ctx context.Context, msg string, kv ...any) {
+	lg.outputCtx(ctx, 2, WARNING, msg, kv...)
+}
+
+// ErrorCtx records an ERROR entry to the log file
+// with the trace and span ids of the context 'ctx'
+func (lg *Logger) ErrorCtx(ctx context.Context, msg string, kv ...any) {
+	lg.outputCtx(ctx, 2, ERROR, msg, kv...)
+}
+
+// FatalCtx records a FATAL entry to the log file
+// with the trace and span ids of the context 'ctx'
+// and exits application using os.Exit(1)
+func (lg *Logger) FatalCtx(ctx context.Context, msg string, kv ...any) {
+	lg.outputCtx(ctx, 2, FATAL, msg, kv...)
+	lg.flushWithin(exitFlushTimeout)
+	os.Exit(1)
+}
+
+// LogfCtx records an entry to the log file
+// with the trace and span ids of the context 'ctx'.
+// Arguments are handled in the manner of fmt.Printf
+func (lg *Logger) LogfCtx(ctx context.Context, l Level, format string, a ...any) {
+	if lg.Enabled(l) {
+		lg.outputCtx(ctx, 2, l, fmt.Sprintf(format, a...))
+	}
+}
+
+// LogCtx records an entry to the log of the logger
+// carried by the context 'ctx' using the Level 'l',
+// 'msg' message provided, the key/value fields in 'kv'
+// and the trace and span ids of the context
+func LogCtx(ctx context.Context, l Level, msg string, kv ...any) {
+	FromContext(ctx).outputCtx(ctx, 2, l, msg, kv...)
+}
+
+// TraceCtx records a TRACE entry to the log of the
+// logger carried by the context 'ctx'
+func TraceCtx(ctx context.Context, msg string, kv ...any) {
+	FromContext(ctx).outputCtx(ctx, 2, TRACE, msg, kv...)
+}
+
+// InfoCtx records an INFO entry to the log of the
+// logger carried by the context 'ctx'
+func InfoCtx(ctx context.Context, msg string, kv ...any) {
+	FromContext(ctx).outputCtx(ctx, 2, INFO, msg, kv...)
+}
+
+// WarningCtx records a WARNING entry to the log of the
+// logger carried by the context 'ctx'
+func WarningCtx(ctx context.Context, msg string, kv ...any) {
+	FromContext(ctx).outputCtx(ctx, 2, WARNING, msg, kv...)
+}
+
+// ErrorCtx records an ERROR entry to the log of the
+// logger carried by the context 'ctx'
+func ErrorCtx(ctx context.Context, msg string, kv ...any) {
+	FromContext(ctx).outputCtx(ctx, 2, ERROR, msg, kv...)
+}
+
+// FatalCtx records a FATAL entry to the log of the
+// logger carried by the context 'ctx'
+// and exits application using os.Exit(1)
+func FatalCtx(ctx context.Context, msg string, kv ...any) {
+	lg := FromContext(ctx)
+	lg.outputCtx(ctx, 2, FATAL, msg, kv...)
+	lg.flushWithin(exitFlushTimeout)
+	os.Exit(1)
+}
+
+// LogfCtx records an entry to the log of the
+// logger carried by the context 'ctx'.
+// Arguments are handled in the manner of fmt.Printf
+func LogfCtx(ctx context.Context, l Level, format string, a ...any) {
+	if lg := FromContext(ctx); lg.Enabled(l) {
+		lg.outputCtx(ctx, 2, l, fmt.Sprintf(format, a...))
+	}
+}
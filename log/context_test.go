@@ -0,0 +1,54 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	b := new(bytes.Buffer)
+	lg := New(WithWriter(b), WithSession("s1"), WithDelim("|"),
+		WithFormat(LogLevel, LogSession, LogTraceID, LogSpanID, LogMessage))
+	ctx := NewContext(context.Background(), lg.With("request", "r1"))
+	if FromContext(context.Background()) != Default() {
+		t.Fatal("FromContext did not return default logger for empty context")
+	}
+	InfoCtx(ctx, "untraced")
+	ctx = ContextWithTrace(ctx, "t1", "p1")
+	WarningCtx(ctx, "traced", "n", 1)
+	lg.ErrorCtx(ctx, "direct")
+	exp := "INFO|s1|-|-|untraced|request=r1\n" +
+		"WARNING|s1|t1|p1|traced|request=r1|n=1\n" +
+		"ERROR|s1|t1|p1|direct\n"
+	if r := b.String(); r != exp {
+		t.Fatalf("context log functions did not post logger fields and trace ids: %q", r)
+	}
+	b.Reset()
+	lg = New(WithWriter(b), WithSession("s1"), WithDelim("|"), WithDateTimeFormat("2006"))
+	lg.InfoCtx(ctx, "default")
+	lg.Info("untraced")
+	r := b.String()
+	if !strings.Contains(r, "|s1|trace=t1|span=p1|context_test.go:") || !strings.Contains(r, "|s1|context_test.go:") {
+		t.Fatalf("default format did not post trace ids after session: %q", r)
+	}
+	s := lg.Scan(strings.NewReader(r))
+	if !s.Next() || s.Record().TraceID != "t1" || s.Record().SpanID != "p1" || s.Record().Message != "default" ||
+		!s.Next() || s.Record().TraceID != "" || s.Record().Message != "untraced" {
+		t.Fatalf("could not scan trace ids of default format: %v", s.Err())
+	}
+	for _, f := range []int{LogJsonFmt, LogLogfmt} {
+		b.Reset()
+		lg = New(WithWriter(b), WithSession("s1"), WithFormat(f))
+		lg.InfoCtx(ctx, "default")
+		if r := b.String(); !strings.Contains(r, "t1") || !strings.Contains(r, "p1") {
+			t.Fatalf("default format did not post trace ids: %q", r)
+		}
+	}
+}
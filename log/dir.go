@@ -0,0 +1,339 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bufio"
+	"compress/gzip"
+	"container/heap"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultMaxOpen is the default max
+// log files open at once by a DirScanner
+const defaultMaxOpen = 64
+
+// DirScanner is an iterator of the records of every
+// log file in a directory, merged in timestamp order
+type DirScanner struct {
+	files   []string     // the paths of the log files
+	opts    []ScanOption // the options of the scanners of the log files
+	max     int          // the max log files open at once
+	scans   []*dirScan   // the scanners of the log files, in the order of the files
+	open    []*dirScan   // the scanners of the open log files, least recently used first
+	queue   scanQueue    // the scanners ordered by their next record
+	cur     *dirScan     // the scanner of the current record
+	err     error        // the error which stopped the scanner
+	started bool         // if true, the scanners have been primed
+}
+
+// dirScan is the Scanner of a log file in a directory
+type dirScan struct {
+	*Scanner
+	file int           // the index of the log file
+	rc   io.ReadCloser // the log file, if open
+}
+
+// ScanDir creates a DirScanner of the records of the
+// log files in the directory 'dir', including rotated
+// and gzipped log files, in the manner of Scan. Records
+// of the files are merged in timestamp order, with records
+// of the same time in the order of the files and lines.
+// Log files are opened as they are read and at most
+// 64 at once, or the max set by ScanMaxOpen, closing
+// and reopening the least recently read files
+func ScanDir(dir string, opts ...ScanOption) (*DirScanner, error) {
+	files, err := logFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &Scanner{}
+	for _, o := range opts {
+		o(s)
+	}
+	d := &DirScanner{files: files, opts: opts, max: s.maxOpen}
+	if d.max <= 0 {
+		d.max = defaultMaxOpen
+	}
+	for i := range files {
+		d.scans = append(d.scans, &dirScan{file: i})
+	}
+	d.queue = append(d.queue, d.scans...)
+	return d, nil
+}
+
+// ScanMaxOpen sets the max log files
+// open at once by a DirScanner
+func ScanMaxOpen(n int) ScanOption {
+	return func(s *Scanner) { s.maxOpen = n }
+}
+
+// ScanDir creates a DirScanner of the records of the
+// log files in the log directory of the logger
+// using the format of the logger
+func (lg *Logger) ScanDir(opts ...ScanOption) (*DirScanner, error) {
+	lg.mu.Lock()
+	dir, enc := lg.dir, lg.encoder()
+	lg.mu.Unlock()
+	return ScanDir(dir, append([]ScanOption{func(s *Scanner) { s.enc = enc }}, opts...)...)
+}
+
+// ReadDir returns the records of the log files in
+// the directory 'dir' merged in timestamp order
+func ReadDir(dir string, opts ...ScanOption) ([]*Record, error) {
+	d, err := ScanDir(dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	var rs []*Record
+	for d.Next() {
+		rs = append(rs, d.Record())
+	}
+	return rs, d.Err()
+}
+
+// Next advances the scanner to the earliest next
+// record of the log files, which is then available
+// through Record. It returns false when every log
+// file ends or a line of a log file cannot be parsed
+func (d *DirScanner) Next() bool {
+	if d.err != nil {
+		return false
+	}
+	if !d.started {
+		d.started = true
+		q := d.queue[:0]
+		for _, s := range d.queue {
+			if d.advance(s) {
+				q = append(q, s)
+			}
+		}
+		d.queue = q
+		heap.Init(&d.queue)
+	} else if d.cur != nil {
+		if d.advance(d.cur) {
+			heap.Push(&d.queue, d.cur)
+		}
+		d.cur = nil
+	}
+	if d.err != nil || len(d.queue) == 0 {
+		return false
+	}
+	d.cur = heap.Pop(&d.queue).(*dirScan)
+	return true
+}
+
+// advance advances the scanner 's', opening its
+// log file if closed, and evaluates whether it has
+// a next record. The log file is closed when it ends
+func (d *DirScanner) advance(s *dirScan) bool {
+	if err := d.use(s); err != nil {
+		if d.err == nil {
+			d.err = err
+		}
+		return false
+	}
+	if s.Next() {
+		return true
+	}
+	if err := s.Err(); err != nil && d.err == nil {
+		if pe, ok := err.(*ParseError); ok {
+			pe.File = d.files[s.file]
+		}
+		d.err = err
+	}
+	d.closeFile(s)
+	return false
+}
+
+// use opens the log file of the scanner 's', if
+// closed, at the offset read by the scanner, closing
+// the least recently used log file if at the max open,
+// and marks the file as the most recently used
+func (d *DirScanner) use(s *dirScan) error {
+	if s.rc != nil {
+		d.unlist(s)
+		d.open = append(d.open, s)
+		return nil
+	}
+	if len(d.open) >= d.max {
+		d.closeFile(d.open[0])
+	}
+	name := d.files[s.file]
+	rc, err := openLog(name)
+	if err != nil {
+		return err
+	}
+	if s.Scanner == nil {
+		s.Scanner = Scan(rc, d.opts...)
+		if f := s.skip; f != nil {
+			s.skip = func(pe *ParseError) {
+				pe.File = name
+				f(pe)
+			}
+		}
+	} else {
+		if sk, ok := rc.(io.Seeker); ok {
+			_, err = sk.Seek(s.off, io.SeekStart)
+		} else {
+			_, err = io.CopyN(io.Discard, rc, s.off)
+		}
+		if err != nil {
+			rc.Close()
+			return err
+		}
+		s.r = bufio.NewReader(rc)
+	}
+	s.rc = rc
+	d.open = append(d.open, s)
+	return nil
+}
+
+// closeFile closes the log file of the scanner 's'
+func (d *DirScanner) closeFile(s *dirScan) error {
+	if s.rc == nil {
+		return nil
+	}
+	d.unlist(s)
+	err := s.rc.Close()
+	s.rc = nil
+	return err
+}
+
+// unlist removes the scanner 's' from the open scanners
+func (d *DirScanner) unlist(s *dirScan) {
+	for i, o := range d.open {
+		if o == s {
+			d.open = append(d.open[:i], d.open[i+1:]...)
+			return
+		}
+	}
+}
+
+// Record returns the current record of the scanner
+func (d *DirScanner) Record() *Record {
+	if d.cur == nil {
+		return nil
+	}
+	return d.cur.Record()
+}
+
+// File returns the path of the log
+// file of the current record
+func (d *DirScanner) File() string {
+	if d.cur == nil {
+		return ""
+	}
+	return d.files[d.cur.file]
+}
+
+// Offsets returns the bytes of each log file read
+// by the scanner, by path, so that records appended
+// to the log files can be read from where it ended
+func (d *DirScanner) Offsets() map[string]int64 {
+	offs := map[string]int64{}
+	for _, s := range d.scans {
+		offs[d.files[s.file]] = 0
+		if s.Scanner != nil {
+			offs[d.files[s.file]] = s.off
+		}
+	}
+	return offs
+}
+
+// Err returns the error which stopped the scanner, if any
+func (d *DirScanner) Err() error {
+	return d.err
+}
+
+// Close closes the open log files of the scanner
+func (d *DirScanner) Close() error {
+	var err error
+	for len(d.open) > 0 {
+		if e := d.closeFile(d.open[0]); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// scanQueue is a heap of scanners
+// ordered by the time of their records
+type scanQueue []*dirScan
+
+func (q scanQueue) Len() int { return len(q) }
+
+func (q scanQueue) Less(i, j int) bool {
+	ti, tj := q[i].Record().Time, q[j].Record().Time
+	if !ti.Equal(tj) {
+		return ti.Before(tj)
+	}
+	return q[i].file < q[j].file
+}
+
+func (q scanQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *scanQueue) Push(x any) { *q = append(*q, x.(*dirScan)) }
+
+func (q *scanQueue) Pop() any {
+	old := *q
+	s := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return s
+}
+
+// logFiles returns the paths of the log files
+// and gzipped log files in the directory 'dir'
+func logFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		n := e.Name()
+		if !e.IsDir() && (strings.HasSuffix(n, ".log") || strings.HasSuffix(n, ".log.gz")) {
+			files = append(files, filepath.Join(dir, n))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// gzipFile is a reader of a gzipped file
+// which closes the file when closed
+type gzipFile struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (g *gzipFile) Close() error {
+	g.Reader.Close()
+	return g.f.Close()
+}
+
+// openLog opens the log file at 'name',
+// decompressing the file if gzipped
+func openLog(name string) (io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(name, ".gz") {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &gzipFile{gz, f}, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeLog(t *testing.T, name string, lns ...string) {
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal("could not create log file:", err)
+	}
+	defer f.Close()
+	if !strings.HasSuffix(name, ".gz") {
+		f.WriteString(strings.Join(lns, "\n") + "\n")
+		return
+	}
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte(strings.Join(lns, "\n") + "\n"))
+	gz.Close()
+}
+
+func TestScanDir(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, filepath.Join(dir, "s1-20220101-000000.000.log.gz"),
+		"INFO \t2022-01-01 10:00:00.000 \ts1 \ta.go:1 \tone",
+		"INFO \t2022-01-01 10:00:03.000 \ts1 \ta.go:2 \tfour")
+	writeLog(t, filepath.Join(dir, "s1.log"),
+		"ERROR \t2022-01-01 10:00:05.000 \ts1 \ta.go:3 \tsix")
+	writeLog(t, filepath.Join(dir, "s2.log"),
+		`{"level":"INFO","datetime":"2022-01-01 10:00:01.000","session":"s2","host":"h2","message":"two"}`,
+		`{"level":"WARNING","datetime":"2022-01-01 10:00:02.000","session":"s2","host":"h2","message":"three"}`,
+		`{"level":"INFO","datetime":"2022-01-01 10:00:04.000","session":"s2","host":"h2","message":"five"}`)
+	writeLog(t, filepath.Join(dir, "notes.txt"), "not a log")
+	for _, tc := range []struct {
+		f   Filter
+		max int
+		exp string
+	}{
+		{Filter{}, 0, "one,two,three,four,five,six"},
+		{Filter{}, 1, "one,two,three,four,five,six"},
+		{Filter{Session: "s1"}, 2, "one,four,six"},
+		{Filter{Host: "h2", MinLevel: WARNING}, 0, "three"},
+	} {
+		rs, err := ReadDir(dir, ScanFilter(tc.f), ScanMaxOpen(tc.max))
+		if err != nil {
+			t.Fatal("could not read log directory:", err)
+		}
+		var msgs []string
+		for _, r := range rs {
+			msgs = append(msgs, r.Message)
+		}
+		if r := strings.Join(msgs, ","); r != tc.exp {
+			t.Fatalf("ReadDir with filter %+v returned %q, expected %q", tc.f, r, tc.exp)
+		}
+	}
+	d, _ := ScanDir(dir)
+	defer d.Close()
+	if !d.Next() || filepath.Base(d.File()) != "s1-20220101-000000.000.log.gz" {
+		t.Fatal("DirScanner did not return file of record")
+	}
+	d, _ = ScanDir(dir, ScanMaxOpen(2))
+	defer d.Close()
+	for d.Next() {
+		if len(d.open) > 2 {
+			t.Fatalf("DirScanner opened %d log files", len(d.open))
+		}
+	}
+	if offs := d.Offsets(); offs[filepath.Join(dir, "s1.log")] != 48 || len(offs) != 3 {
+		t.Fatalf("DirScanner did not return offsets of log files read: %v", offs)
+	}
+}
+
+func TestScanDirErrors(t *testing.T) {
+	if _, err := ScanDir(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Fatal("ScanDir did not return error for missing directory")
+	}
+	dir := t.TempDir()
+	writeLog(t, filepath.Join(dir, "bad.log"), "BAD \t- \t- \t- \tmsg")
+	_, err := ReadDir(dir)
+	var pe *ParseError
+	if !errors.As(err, &pe) || filepath.Base(pe.File) != "bad.log" {
+		t.Fatalf("ReadDir did not return parse error of log file: %v", err)
+	}
+	writeLog(t, filepath.Join(dir, "good.log"), "INFO \t2022-01-01 10:00:00.000 \ts1 \ta.go:1 \tone")
+	var skipped []*ParseError
+	rs, err := ReadDir(dir, ScanSkipInvalid(func(err *ParseError) { skipped = append(skipped, err) }))
+	if err != nil || len(rs) != 1 || rs[0].Message != "one" {
+		t.Fatalf("ReadDir did not skip invalid lines: %v %v", rs, err)
+	}
+	if len(skipped) != 1 || filepath.Base(skipped[0].File) != "bad.log" || skipped[0].Line != 1 {
+		t.Fatalf("ReadDir did not report invalid lines skipped: %v", skipped)
+	}
+}
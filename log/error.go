@@ -0,0 +1,144 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// errKey is the key of the field of an error
+const errKey = "error"
+
+// stackKey is the key of the stack trace
+// of a record posted to the log
+const stackKey = "stack"
+
+// maxStack is the maximum number of
+// frames of a stack trace posted to log
+const maxStack = 64
+
+// Err creates a Field of the error 'err'.
+// Errors are posted as their message in the
+// delimited format and in the json format as
+// their message, type and chain of causes
+// unwrapped with errors.Unwrap and errors.Join
+func Err(err error) Field {
+	return Field{Key: errKey, Value: err}
+}
+
+// errorInfo is the structured form
+// of an error posted in json format
+type errorInfo struct {
+	Message string      `json:"message"`
+	Type    string      `json:"type"`
+	Causes  []errorInfo `json:"causes,omitempty"`
+}
+
+// errorInfoOf converts the error 'err' and
+// the chain of errors it wraps to an errorInfo
+func errorInfoOf(err error) errorInfo {
+	return errorInfoDepth(err, 0)
+}
+
+// errorInfoDepth converts the error 'err' at
+// the depth 'd' of an error chain to an errorInfo,
+// ending the chain at maxStack errors
+func errorInfoDepth(err error, d int) errorInfo {
+	e := errorInfo{Message: errorText(err), Type: fmt.Sprintf("%T", err)}
+	if d >= maxStack || isNilPtr(err) {
+		return e
+	}
+	var causes []error
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		causes = []error{u.Unwrap()}
+	case interface{ Unwrap() []error }:
+		causes = u.Unwrap()
+	}
+	for _, c := range causes {
+		if c != nil {
+			e.Causes = append(e.Causes, errorInfoDepth(c, d+1))
+		}
+	}
+	return e
+}
+
+// errorText returns the message of the error 'err',
+// or '<nil>' if 'err' is a nil pointer, whose Error
+// method may panic, in the manner of fmt
+func errorText(err error) string {
+	if isNilPtr(err) {
+		return "<nil>"
+	}
+	return err.Error()
+}
+
+// stringerText returns the text of the value 'v',
+// or '<nil>' if 'v' is a nil pointer, whose String
+// method may panic, in the manner of fmt
+func stringerText(v fmt.Stringer) string {
+	if isNilPtr(v) {
+		return "<nil>"
+	}
+	return v.String()
+}
+
+// isNilPtr evaluates whether 'v' is
+// a nil pointer of a non-nil interface
+func isNilPtr(v any) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
+
+// stack returns the stack trace of the caller
+// 'skip' levels above stack in the call stack,
+// with each frame as 'function file:line', of
+// at most maxStack frames excluding the runtime
+func stack(skip int) []string {
+	pcs := make([]uintptr, maxStack)
+	n := runtime.Callers(skip+2, pcs)
+	return frames(pcs[:n], false)
+}
+
+// fullStack returns the full stack trace of the
+// goroutine of the caller 'skip' levels above
+// fullStack in the call stack, including the
+// frames of the runtime, such as of a panic
+func fullStack(skip int) []string {
+	pcs := make([]uintptr, maxStack)
+	n := runtime.Callers(skip+2, pcs)
+	for n == len(pcs) {
+		pcs = make([]uintptr, 2*len(pcs))
+		n = runtime.Callers(skip+2, pcs)
+	}
+	return frames(pcs[:n], true)
+}
+
+// frames formats the frames of the program counters
+// 'pcs' as 'function file:line', excluding the
+// frames of the runtime unless 'rt' is true
+func frames(pcs []uintptr, rt bool) []string {
+	fs := runtime.CallersFrames(pcs)
+	var s []string
+	for {
+		f, more := fs.Next()
+		if f.Function != "" && (rt || !isRuntimeFrame(f.Function)) {
+			s = append(s, fmt.Sprint(f.Function, " ", f.File, ":", f.Line))
+		}
+		if !more {
+			return s
+		}
+	}
+}
+
+// isRuntimeFrame evaluates whether the function
+// 'fn' of a frame is of the go runtime
+func isRuntimeFrame(fn string) bool {
+	return strings.HasPrefix(fn, "runtime.")
+}
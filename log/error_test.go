@@ -0,0 +1,95 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestErr(t *testing.T) {
+	b := new(bytes.Buffer)
+	lg := New(WithWriter(b), WithFormat(LogJsonFmt, LogLevel, LogMessage))
+	perr := &fs.PathError{Op: "open", Path: "a.txt", Err: fs.ErrNotExist}
+	err := errors.Join(fmt.Errorf("load config: %w", perr), errors.New("no fallback"))
+	lg.Error("startup failed", Err(err))
+	var m struct {
+		Error errorInfo
+		Stack []string
+	}
+	if e := json.Unmarshal(b.Bytes(), &m); e != nil {
+		t.Fatalf("could not unmarshal json log with error: %v", e)
+	}
+	e := m.Error
+	if e.Message != err.Error() || e.Type != "*errors.joinError" || len(e.Causes) != 2 {
+		t.Fatalf("json log did not post error with joined causes: %+v", e)
+	}
+	if c := e.Causes[0]; c.Type != "*fmt.wrapError" || len(c.Causes) != 1 || c.Causes[0].Type != "*fs.PathError" ||
+		len(c.Causes[0].Causes) != 1 || c.Causes[0].Causes[0].Message != fs.ErrNotExist.Error() {
+		t.Fatalf("json log did not post error chain: %+v", c)
+	}
+	if e.Causes[1].Message != "no fallback" || e.Causes[1].Causes != nil {
+		t.Fatalf("json log did not post joined error: %+v", e.Causes[1])
+	}
+	if m.Stack != nil {
+		t.Fatal("json log posted stack trace without SetStackTrace")
+	}
+	b.Reset()
+	lg = New(WithWriter(b), WithFormat(LogLevel, LogMessage), WithDelim("|"))
+	lg.Error("startup failed", Err(perr))
+	if r := b.String(); r != "ERROR|startup failed|error=\"open a.txt: file does not exist\"\n" {
+		t.Fatalf("std log did not post error message: %q", r)
+	}
+	var nilErr *fs.PathError
+	b.Reset()
+	lg.Error("typed nil", Err(nilErr), Err(fmt.Errorf("wrapped: %w", nilErr)))
+	if r := b.String(); r != "ERROR|typed nil|error=<nil>|error=\"wrapped: <nil>\"\n" {
+		t.Fatalf("std log did not post typed nil error: %q", r)
+	}
+	if e := errorInfoOf(fmt.Errorf("wrapped: %w", nilErr)); len(e.Causes) != 1 || e.Causes[0].Message != "<nil>" {
+		t.Fatalf("json log did not post typed nil cause: %+v", e)
+	}
+}
+
+func TestStackTrace(t *testing.T) {
+	b := new(bytes.Buffer)
+	lg := New(WithWriter(b), WithFormat(LogJsonFmt, LogLevel, LogMessage), WithStackTrace(ERROR))
+	lg.Warning("slow", "stack", "x")
+	lg.Error("failed", "stack", "x")
+	lg.Trace("here")
+	s := Scan(b)
+	for i := 0; s.Next(); i++ {
+		r := s.Record()
+		if i == 0 {
+			if r.Stack != nil || len(r.Fields) != 1 || r.Fields[0] != F("stack", "x") {
+				t.Fatalf("WARNING record posted with stack trace: %+v", r)
+			}
+			continue
+		}
+		if len(r.Stack) == 0 || !strings.HasPrefix(r.Stack[0], "github.com/jcdotter/gosimple/log.TestStackTrace ") ||
+			!strings.Contains(r.Stack[0], "error_test.go:") {
+			t.Fatalf("%s record not posted with stack trace of log call: %v", r.Level, r.Stack)
+		}
+		if i == 1 && (len(r.Fields) != 1 || r.Fields[0] != F("stack", "x")) {
+			t.Fatalf("json log did not prefix field colliding with stack trace: %v", r.Fields)
+		}
+	}
+	if s.Err() != nil {
+		t.Fatal("could not scan records with stack traces:", s.Err())
+	}
+	b.Reset()
+	lg = New(WithWriter(b), WithFormat(LogLevel, LogMessage), WithDelim("|"))
+	lg.Trace("here")
+	s = Scan(b, ScanFormat(LogLevel, LogMessage), ScanDelim("|"))
+	if !s.Next() || len(s.Record().Stack) == 0 || !strings.Contains(s.Record().Stack[0], "TestStackTrace") {
+		t.Fatalf("std log did not post TRACE record with stack trace: %q", b.String())
+	}
+}
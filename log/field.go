@@ -0,0 +1,190 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// badKey is the key of a field value
+// provided without a string key
+const badKey = "!BADKEY"
+
+// Field is a key/value pair of context
+// posted with a log record
+type Field struct {
+	Key   string
+	Value any
+}
+
+// F creates a Field using the key 'k'
+// and value 'v' provided
+func F(k string, v any) Field {
+	return Field{Key: k, Value: v}
+}
+
+// fieldsOf converts the args provided to log
+// functions into fields. Args are provided either
+// as Fields or as alternating keys and values
+func fieldsOf(kv []any) []Field {
+	if len(kv) == 0 {
+		return nil
+	}
+	fs := make([]Field, 0, len(kv)/2+1)
+	for i := 0; i < len(kv); i++ {
+		switch k := kv[i].(type) {
+		case Field:
+			fs = append(fs, k)
+		case string:
+			if i+1 < len(kv) {
+				fs = append(fs, Field{k, kv[i+1]})
+				i++
+			} else {
+				fs = append(fs, Field{badKey, k})
+			}
+		default:
+			fs = append(fs, Field{badKey, k})
+		}
+	}
+	return fs
+}
+
+// value converts the value of field 'f'
+// to the value posted in the log record
+func (f Field) value(timeFmt string) any {
+	switch v := f.Value.(type) {
+	case nil:
+		return nil
+	case error:
+		return errorText(v)
+	case time.Duration:
+		return v.String()
+	case time.Time:
+		return v.Format(timeFmt)
+	case fmt.Stringer:
+		return stringerText(v)
+	}
+	return f.Value
+}
+
+// jsonValue encodes the value of field 'f' to json,
+// encoding errors as their message, type and causes
+// and using the value as a string if it cannot be encoded
+func (f Field) jsonValue(timeFmt string) json.RawMessage {
+	var v any
+	if e, ok := f.Value.(error); ok {
+		v = errorInfoOf(e)
+	} else {
+		v = f.value(timeFmt)
+	}
+	r, err := json.Marshal(v)
+	if err != nil {
+		r, _ = json.Marshal(fmt.Sprint(v))
+	}
+	return r
+}
+
+// stdValue formats the value of field 'f' as text,
+// quoting the value if it is empty or contains
+// spaces, quotes, '=' or the delimiter 'delim'
+func (f Field) stdValue(timeFmt, delim string) string {
+	v := fmt.Sprint(f.value(timeFmt))
+	if needsQuote(v, delim) {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// needsQuote evaluates whether the text value 'v'
+// must be quoted to be parsed from a delimited record
+func needsQuote(v, delim string) bool {
+	if v == "" || (delim != "" && strings.Contains(v, delim)) {
+		return true
+	}
+	for _, r := range v {
+		if unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitStd splits the delimited log record 'ln'
+// into its elements, keeping quoted values
+// and quoted key="value" fields intact
+func splitStd(ln, delim string) []string {
+	var els []string
+	for {
+		if p := quoteStart(ln, delim); p >= 0 {
+			if q, err := strconv.QuotedPrefix(ln[p:]); err == nil {
+				if r := ln[p+len(q):]; r == "" || strings.HasPrefix(r, delim) {
+					els = append(els, ln[:p+len(q)])
+					if r == "" {
+						return els
+					}
+					ln = r[len(delim):]
+					continue
+				}
+			}
+		}
+		i := strings.Index(ln, delim)
+		if i < 0 || delim == "" {
+			return append(els, ln)
+		}
+		els = append(els, ln[:i])
+		ln = ln[i+len(delim):]
+	}
+}
+
+// quoteStart returns the index of the quoted value
+// at the start of the delimited record 'ln', which is
+// either the record or the value of a key="value" field,
+// or -1 if the record does not start with a quoted value
+func quoteStart(ln, delim string) int {
+	if strings.HasPrefix(ln, `"`) {
+		return 0
+	}
+	i := strings.Index(ln, `="`)
+	if i < 1 || strings.ContainsAny(ln[:i], ` "`) || (delim != "" && strings.Contains(ln[:i], delim)) {
+		return -1
+	}
+	return i + 1
+}
+
+// stdElement formats the element value 'v' of a
+// delimited record, posting empty values as '-' and
+// quoting values which could not be parsed otherwise
+func stdElement(v, delim string) string {
+	switch {
+	case v == "":
+		return emptyEl
+	case v == emptyEl, strings.HasPrefix(v, `"`), strings.ContainsAny(v, "\r\n"),
+		delim != "" && strings.Contains(v, delim):
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// parseField parses a key=value element
+// of a delimited log record
+func parseField(el string) (k, v string, ok bool) {
+	i := strings.Index(el, "=")
+	if i < 1 {
+		return "", "", false
+	}
+	k, v = el[:i], el[i+1:]
+	if strings.HasPrefix(v, `"`) {
+		if u, err := strconv.Unquote(v); err == nil {
+			v = u
+		}
+	}
+	return k, v, true
+}
@@ -0,0 +1,321 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fieldsEl is the placeholder of the
+// key=value fields of a template
+const fieldsEl = "fields"
+
+// ErrTemplateFields is returned by handlers writing a
+// record with fields or a stack trace in a template
+// without a {fields} placeholder, which omits them
+var ErrTemplateFields = errors.New("log template has no {fields} placeholder for the fields of the record")
+
+// buildLogfmt is a helper function to encode
+// builds a logfmt log line of the non-empty elements
+// in the format, with the trace and span ids of the
+// record after the session, followed by the fields as key=value
+// pairs separated by spaces. Fields with the key of
+// an element are posted with the key prefixed
+// by 'fields.' in the manner of buildJsonLog
+func (e *encoder) buildLogfmt(els map[string]string, fields []Field, stack []string) []byte {
+	var pairs []string
+	for _, el := range e.format {
+		if v := els[elNames[el]]; v != "" {
+			pairs = append(pairs, elNames[el]+"="+logfmtValue(v))
+		}
+		for _, id := range e.ctxElements(el, els) {
+			pairs = append(pairs, elNames[id]+"="+logfmtValue(els[elNames[id]]))
+		}
+	}
+	pairs = append(pairs, fieldPairs(els, fields, stack, e.timeFmt)...)
+	return []byte(strings.Join(pairs, " "))
+}
+
+// fieldPairs formats the fields and the stack trace
+// of a record as key=value pairs. Fields with the key
+// of an element or of the stack trace are posted with
+// the key prefixed by 'fields.'
+func fieldPairs(els map[string]string, fields []Field, stack []string, timeFmt string) []string {
+	var pairs []string
+	for _, f := range fields {
+		k := f.Key
+		if _, ok := els[k]; ok || (k == stackKey && len(stack) > 0) {
+			k = "fields." + k
+		}
+		pairs = append(pairs, k+"="+f.stdValue(timeFmt, ""))
+	}
+	if len(stack) > 0 {
+		pairs = append(pairs, stackKey+"="+strconv.Quote(strings.Join(stack, "\n")))
+	}
+	return pairs
+}
+
+// logfmtValue quotes the logfmt value 'v'
+// if it could not be parsed otherwise
+func logfmtValue(v string) string {
+	if needsQuote(v, "") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// parseLogfmt parses the logfmt log line 'ln' to a record
+func (e *encoder) parseLogfmt(ln string) (*Record, error) {
+	r := &Record{}
+	for _, p := range splitStd(ln, " ") {
+		if p == "" {
+			continue
+		}
+		k, v, ok := parseField(p)
+		if !ok {
+			return nil, errors.New("invalid logfmt pair " + strconv.Quote(p))
+		}
+		if err := e.setPair(r, k, v); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// setPair sets the element, stack trace or field
+// of the record 'r' of the key=value pair 'k' and 'v'
+func (e *encoder) setPair(r *Record, k, v string) error {
+	switch {
+	case isElName(k):
+		return e.setElement(r, k, v)
+	case k == stackKey:
+		r.Stack = strings.Split(v, "\n")
+	default:
+		r.Fields = append(r.Fields, Field{strings.TrimPrefix(k, "fields."), v})
+	}
+	return nil
+}
+
+// template is a user-defined log format of text
+// and placeholders of the elements of a record,
+// such as "{datetime} [{level}] {source} {message} {fields}"
+type template struct {
+	text  string         // the template text
+	parts []tmplPart     // the text and placeholders of the template
+	re    *regexp.Regexp // the parser of log lines in the template format
+	els   []string       // the elements of the capture groups of the parser
+}
+
+// tmplPart is either text or the
+// placeholder of an element of a template
+type tmplPart struct {
+	text string // the text of the part, if not a placeholder
+	el   string // the element name of the placeholder, if any
+}
+
+// quotedPat matches a quoted value
+const quotedPat = `"(?:[^"\\]|\\.)*"`
+
+// fieldsPat matches the key=value pairs of the fields of a record
+const fieldsPat = `[^\s=]+=(?:` + quotedPat + `|[^\s"]*)(?: [^\s=]+=(?:` + quotedPat + `|[^\s"]*))*`
+
+// tmplPlaceholder matches the placeholders of a template
+var tmplPlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// newTemplate parses the template text 't'. Placeholders
+// of the names in elNames and {fields} are replaced by the
+// elements and fields of a record, other text is posted as is
+func newTemplate(t string) *template {
+	tp := &template{text: t}
+	i := 0
+	for _, m := range tmplPlaceholder.FindAllStringSubmatchIndex(t, -1) {
+		if n := t[m[2]:m[3]]; isElName(n) || n == fieldsEl {
+			if m[0] > i {
+				tp.parts = append(tp.parts, tmplPart{text: t[i:m[0]]})
+			}
+			tp.parts = append(tp.parts, tmplPart{el: n})
+			i = m[1]
+		}
+	}
+	if i < len(t) {
+		tp.parts = append(tp.parts, tmplPart{text: t[i:]})
+	}
+	pat := "^"
+	for i, p := range tp.parts {
+		switch {
+		case p.el == fieldsEl:
+			// the text before the fields is omitted without fields
+			pat += "(?:" + regexp.QuoteMeta(tp.before(i)) + "(" + fieldsPat + "))?"
+		case p.el != "":
+			pat += "(" + quotedPat + "|.*?)"
+		case i+1 < len(tp.parts) && tp.parts[i+1].el == fieldsEl:
+		default:
+			pat += regexp.QuoteMeta(p.text)
+		}
+		if p.el != "" {
+			tp.els = append(tp.els, p.el)
+		}
+	}
+	tp.re = regexp.MustCompile(pat + "$")
+	return tp
+}
+
+// before returns the text of the part
+// before the part 'i' of the template, if any
+func (t *template) before(i int) string {
+	if i > 0 && t.parts[i-1].el == "" {
+		return t.parts[i-1].text
+	}
+	return ""
+}
+
+// build is a helper function to encode which
+// builds a log line in the template format. Empty
+// elements are posted as '-' and elements are quoted
+// if they could not be parsed from the log line.
+// It returns ErrTemplateFields with the line if the
+// template omits the fields or stack trace
+func (t *template) build(els map[string]string, fields []Field, stack []string, timeFmt string) ([]byte, error) {
+	var log string
+	omitted := len(fields) > 0 || len(stack) > 0
+	for i, p := range t.parts {
+		switch {
+		case p.el == fieldsEl:
+			if pairs := fieldPairs(els, fields, stack, timeFmt); len(pairs) > 0 {
+				log += t.before(i) + strings.Join(pairs, " ")
+			}
+			omitted = false
+		case p.el != "":
+			log += t.element(els[p.el], i)
+		case i+1 < len(t.parts) && t.parts[i+1].el == fieldsEl:
+		default:
+			log += p.text
+		}
+	}
+	if omitted {
+		return []byte(log), ErrTemplateFields
+	}
+	return []byte(log), nil
+}
+
+// element formats the value 'v' of the placeholder
+// 'i' of the template, quoting values containing the
+// text after the placeholder or, if the fields follow
+// the placeholder, values which could be parsed as fields
+func (t *template) element(v string, i int) string {
+	next, fields := "", false
+	if i+1 < len(t.parts) {
+		next = t.parts[i+1].text
+		fields = t.parts[i+1].el == fieldsEl || (i+2 < len(t.parts) && t.parts[i+2].el == fieldsEl)
+	}
+	switch {
+	case v == "":
+		return emptyEl
+	case v == emptyEl, strings.HasPrefix(v, `"`), strings.ContainsAny(v, "\r\n"),
+		fields && strings.Contains(v, "="), !fields && next != "" && strings.Contains(v, next):
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// parseTemplate parses the log line 'ln'
+// in the template format to a record
+func (e *encoder) parseTemplate(ln string) (*Record, error) {
+	m := e.tmpl.re.FindStringSubmatch(ln)
+	if m == nil {
+		return nil, errors.New("log line does not match template " + strconv.Quote(e.tmpl.text))
+	}
+	r := &Record{}
+	for i, el := range e.tmpl.els {
+		v := m[i+1]
+		if el == fieldsEl {
+			for _, p := range splitStd(v, " ") {
+				if p == "" {
+					continue
+				}
+				k, v, ok := parseField(p)
+				if !ok {
+					return nil, errors.New("invalid template field " + strconv.Quote(p))
+				}
+				if err := e.setPair(r, k, v); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if v == emptyEl {
+			continue
+		}
+		if strings.HasPrefix(v, `"`) {
+			u, err := strconv.Unquote(v)
+			if err != nil {
+				return nil, err
+			}
+			v = u
+		}
+		if err := e.setElement(r, el, v); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// WithTemplate sets the template of
+// the records posted by a new Logger
+func WithTemplate(t string) Option {
+	return func(lg *Logger) { lg.SetTemplate(t) }
+}
+
+// SetTemplate sets a user-defined format of the records
+// posted to log, such as "{datetime} [{level}] {message} {fields}".
+// Placeholders of the element names, such as {level},
+// {datetime}, {session} or {trace}, are replaced by the
+// elements of the record and {fields} by the key=value
+// fields of the record. Other text is posted as is.
+// A template without {fields} omits the fields and stack
+// traces of records, which is reported once as a write
+// error wrapping ErrTemplateFields.
+// The template overides the format set by SetFormat
+// until LogJsonFmt, LogStdFmt or LogLogfmt is set
+func (lg *Logger) SetTemplate(t string) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.tmpl = newTemplate(t)
+		lg.jsonFmt, lg.logfmt = false, false
+	}
+}
+
+// SetTemplate sets a user-defined
+// format of the default log
+func SetTemplate(t string) {
+	Default().SetTemplate(t)
+}
+
+// HandlerTemplate sets a user-defined format of the
+// records written by the handler in the manner of SetTemplate
+func HandlerTemplate(t string) HandlerOption {
+	return func(h *WriterHandler) { h.enc.setFmt(logTmplFmt, newTemplate(t)) }
+}
+
+// ScanTemplate sets the user-defined format of the
+// records read by the Scanner in the manner of SetTemplate
+func ScanTemplate(t string) ScanOption {
+	return func(s *Scanner) { s.enc.setFmt(logTmplFmt, newTemplate(t)) }
+}
+
+// setFmt sets the line format of the encoder
+// to LogJsonFmt, LogStdFmt, LogLogfmt or the
+// template 't' if the format is logTmplFmt
+func (e *encoder) setFmt(f int, t *template) {
+	e.jsonFmt, e.logfmt, e.tmpl = f == LogJsonFmt, f == LogLogfmt, nil
+	if f == logTmplFmt {
+		e.tmpl = t
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var fmtRecords = []Record{
+	{Level: INFO, Time: time.Date(2022, 1, 1, 10, 0, 0, 0, time.Local), Source: "main.go:12", Session: "s1",
+		Message: "payment settled", Fields: []Field{F("order", "42"), F("note", "two words")}},
+	{Level: ERROR, Time: time.Date(2022, 1, 1, 10, 0, 1, 0, time.Local), Source: "main.go:20", Session: "s1",
+		Message: "retry a=b [x]", Fields: []Field{F("level", "x"), F("empty", "")}, Stack: []string{"main.main main.go:20"}},
+	{Level: WARNING, Time: time.Date(2022, 1, 1, 10, 0, 2, 0, time.Local), Source: "main.go:30", Message: "-"},
+}
+
+// roundTrip encodes the records in the format of the
+// handler options and scans them with the scan options
+func roundTrip(t *testing.T, hopts []HandlerOption, sopts []ScanOption) string {
+	b := new(bytes.Buffer)
+	h := NewWriterHandler(b, hopts...)
+	for i := range fmtRecords {
+		h.Handle(&fmtRecords[i])
+	}
+	s := Scan(bytes.NewReader(b.Bytes()), sopts...)
+	for i := 0; s.Next(); i++ {
+		if r := s.Record(); !reflect.DeepEqual(*r, fmtRecords[i]) {
+			t.Fatalf("record %d did not round trip:\n%s\n%+v", i, b, *r)
+		}
+	}
+	if s.Err() != nil {
+		t.Fatalf("could not scan records:\n%s\n%v", b, s.Err())
+	}
+	return b.String()
+}
+
+func TestLogfmt(t *testing.T) {
+	f := []int{LogLevel, LogDateTime, LogSession, LogSource, LogMessage}
+	out := roundTrip(t, []HandlerOption{HandlerFormat(append(f, LogLogfmt)...)}, []ScanOption{ScanFormat(LogLogfmt)})
+	exp := `level=INFO datetime="2022-01-01 10:00:00.000" session=s1 source=main.go:12 message="payment settled" order=42 note="two words"` + "\n"
+	if out[:len(exp)] != exp {
+		t.Fatalf("logfmt record not written as key=value pairs: %q", out)
+	}
+	lg := New(WithWriter(new(bytes.Buffer)), WithFormat(LogJsonFmt, LogLogfmt))
+	if lg.jsonFmt || !lg.logfmt || !lg.encoder().logfmt {
+		t.Fatal("SetFormat did not set the logfmt format")
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tmpl := "{datetime} [{level}] {session} {source} {message} {fields}"
+	out := roundTrip(t, []HandlerOption{HandlerTemplate(tmpl)}, []ScanOption{ScanTemplate(tmpl)})
+	exp := "2022-01-01 10:00:00.000 [INFO] s1 main.go:12 payment settled order=42 note=\"two words\"\n" +
+		"2022-01-01 10:00:01.000 [ERROR] s1 main.go:20 \"retry a=b [x]\" fields.level=x empty=\"\" stack=\"main.main main.go:20\"\n" +
+		"2022-01-01 10:00:02.000 [WARNING] - main.go:30 \"-\"\n"
+	if out != exp {
+		t.Fatalf("template records not written in template format:\n%s", out)
+	}
+	roundTrip(t, []HandlerOption{HandlerTemplate("{level}|{datetime}|{fields}|{message}|{session}|{source}")},
+		[]ScanOption{ScanTemplate("{level}|{datetime}|{fields}|{message}|{session}|{source}")})
+	b := new(bytes.Buffer)
+	lg := New(WithWriter(b), WithTemplate("<{level}> {message} {unknown}"))
+	lg.Info("ready")
+	if b.String() != "<INFO> ready {unknown}\n" {
+		t.Fatalf("template did not post unknown placeholders as text: %q", b)
+	}
+	if s := Scan(bytes.NewBufferString("INFO ready"), ScanTemplate("<{level}> {message}")); s.Next() || s.Err() == nil {
+		t.Fatal("template parser did not reject line not matching the template")
+	}
+	if s := Scan(bytes.NewBufferString("INFO ready level=LOUD"), ScanTemplate("{level} {message} {fields}")); s.Next() || s.Err() == nil {
+		t.Fatal("template parser did not reject invalid fields")
+	}
+	var errs []error
+	b.Reset()
+	lg = New(WithWriter(b), WithTemplate("{{level}} {message}"), WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	lg.Info("ready")
+	lg.Info("x", "k", "v")
+	lg.Info("y", "k", "v")
+	if len(errs) != 1 || !errors.Is(errs[0], ErrTemplateFields) || lg.Stats().WriteErrors != 1 {
+		t.Fatalf("template did not report omitted fields: %v", errs)
+	}
+	s := lg.Scan(strings.NewReader(b.String()))
+	if !s.Next() || s.Record().Message != "ready" || !s.Next() || s.Record().Level != INFO || !s.Next() || s.Err() != nil {
+		t.Fatalf("template parser did not parse line starting with a brace: %v", s.Err())
+	}
+}
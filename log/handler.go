@@ -0,0 +1,344 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is a structured log record
+// posted by a Logger to its Handler
+type Record struct {
+	Level   Level     // the level of the record
+	Time    time.Time // the time the record was posted
+	Source  string    // the full source file path and line of the record
+	Session string    // the log session of the logger
+	Host    string    // the source host server of the logger
+	Service string    // the source service of the logger
+	TraceID string    // the trace id from the context, if any
+	SpanID  string    // the span id from the context, if any
+	Message string    // the log message
+	Fields  []Field   // the key/value fields of the record
+	Stack   []string  // the stack trace of the log call, if captured
+}
+
+// elements returns the elements of the record
+// by element name using the datetime format 'timeFmt'
+func (r *Record) elements(timeFmt string) map[string]string {
+	return map[string]string{
+		"level":      levelNames[r.Level],
+		"datetime":   r.Time.Format(timeFmt),
+		"session":    r.Session,
+		"host":       r.Host,
+		"service":    r.Service,
+		"fullsource": r.Source,
+		"source":     r.Source[strings.LastIndex(r.Source, "/")+1:],
+		"message":    r.Message,
+		"trace":      r.TraceID,
+		"span":       r.SpanID,
+	}
+}
+
+// Handler receives the structured records
+// posted by a Logger and writes them to a sink
+type Handler interface {
+	// Enabled evaluates whether records of
+	// Level 'l' are handled by the handler
+	Enabled(l Level) bool
+	// Handle writes the record 'r' to the sink
+	// of the handler. The record must not be
+	// retained after Handle returns
+	Handle(r *Record) error
+}
+
+// encoder formats records to the
+// delimited or json log format
+type encoder struct {
+	format  []int     // the format for a log line
+	delim   string    // the delimeter between log line elements
+	timeFmt string    // the date format posted to log
+	jsonFmt bool      // if true, post log line in json format
+	logfmt  bool      // if true, post log line in logfmt format
+	tmpl    *template // the user-defined format of a log line, if any
+}
+
+// encode formats the record 'r' to a log line,
+// returning ErrTemplateFields with the line if the
+// template of the encoder omits the fields of the record
+func (e *encoder) encode(r *Record) ([]byte, error) {
+	els := r.elements(e.timeFmt)
+	var b []byte
+	var err error
+	switch {
+	case e.jsonFmt:
+		b = e.buildJsonLog(els, r.Fields, r.Stack)
+	case e.logfmt:
+		b = e.buildLogfmt(els, r.Fields, r.Stack)
+	case e.tmpl != nil:
+		b, err = e.tmpl.build(els, r.Fields, r.Stack, e.timeFmt)
+	default:
+		b = e.buildStdLog(els, r.Fields, r.Stack)
+	}
+	return append(b, "\n"...), err
+}
+
+// ctxElements returns the trace and span elements
+// posted after the element 'el' of the format if it is
+// the session, which are the ids of the record in 'els'
+// omitted by the format
+func (e *encoder) ctxElements(el int, els map[string]string) []int {
+	if el != LogSession {
+		return nil
+	}
+	var ids []int
+	for _, id := range []int{LogTraceID, LogSpanID} {
+		if els[elNames[id]] != "" && !e.hasElement(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// hasElement evaluates whether the
+// element 'el' is in the format
+func (e *encoder) hasElement(el int) bool {
+	for _, f := range e.format {
+		if f == el {
+			return true
+		}
+	}
+	return false
+}
+
+// buildStdLog is a helper function to encode
+// builds standard log format using elements in the
+// format followed by the key=value fields
+// separated by the delim. Empty elements
+// are posted as '-' to keep their position and
+// elements containing the delim are quoted.
+// Trace and span ids omitted by the format are
+// posted after the session as key=value elements.
+// The stack trace is posted last as a quoted
+// stack field of newline separated frames
+func (e *encoder) buildStdLog(els map[string]string, fields []Field, stack []string) []byte {
+	var log string
+	for i, el := range e.format {
+		if i > 0 {
+			log += e.delim
+		}
+		log += stdElement(els[elNames[el]], e.delim)
+		for _, id := range e.ctxElements(el, els) {
+			log += e.delim + elNames[id] + "=" + stdElement(els[elNames[id]], e.delim)
+		}
+	}
+	for _, f := range fields {
+		log += e.delim + f.Key + "=" + f.stdValue(e.timeFmt, e.delim)
+	}
+	if len(stack) > 0 {
+		log += e.delim + stackKey + "=" + strconv.Quote(strings.Join(stack, "\n"))
+	}
+	return []byte(log)
+}
+
+// buildJsonLog is a helper function to encode
+// builds a json log format using the elements
+// in the format, the trace and span ids of the
+// record if the format has the session, and the
+// fields as json keys.
+// Fields with the key of an element or of the
+// stack trace are posted with the key prefixed
+// by 'fields.' and the stack trace is posted
+// as an array of frames
+func (e *encoder) buildJsonLog(els map[string]string, fields []Field, stack []string) []byte {
+	log := map[string]any{}
+	for _, el := range e.format {
+		if v := els[elNames[el]]; v != "" {
+			log[elNames[el]] = v
+		}
+		for _, id := range e.ctxElements(el, els) {
+			log[elNames[id]] = els[elNames[id]]
+		}
+	}
+	for _, f := range fields {
+		k := f.Key
+		if _, ok := els[k]; ok || (k == stackKey && len(stack) > 0) {
+			k = "fields." + k
+		}
+		log[k] = f.jsonValue(e.timeFmt)
+	}
+	if len(stack) > 0 {
+		log[stackKey] = stack
+	}
+	r, _ := json.Marshal(log)
+	return r
+}
+
+// WriterHandler is a Handler which formats records
+// and writes each record to an io.Writer in a single write
+type WriterHandler struct {
+	mu    sync.Mutex
+	w     io.Writer // the writer of the handler
+	level Level     // the minimum level written by the handler
+	enc   encoder   // the format of the records written
+}
+
+// HandlerOption configures a WriterHandler
+// created with log.NewWriterHandler
+type HandlerOption func(*WriterHandler)
+
+// NewWriterHandler creates a handler writing records
+// to the writer 'w' at the TRACE level and above in
+// the default log format, overidden by the options provided
+func NewWriterHandler(w io.Writer, opts ...HandlerOption) *WriterHandler {
+	h := &WriterHandler{w: w, enc: defaultEncoder}
+	for _, o := range opts {
+		o(h)
+	}
+	return h
+}
+
+// HandlerLevel sets the minimum level
+// of the records written by the handler
+func HandlerLevel(l Level) HandlerOption {
+	return func(h *WriterHandler) { h.level = l }
+}
+
+// HandlerFormat sets the order and elements of
+// the records written by the handler, including
+// LogJsonFmt, LogStdFmt or LogLogfmt in the manner of SetFormat
+func HandlerFormat(f ...int) HandlerOption {
+	return func(h *WriterHandler) {
+		ft := []int{}
+		for _, i := range f {
+			if _, ok := elNames[i]; ok {
+				ft = append(ft, i)
+			} else if i == LogJsonFmt || i == LogStdFmt || i == LogLogfmt {
+				h.enc.setFmt(i, nil)
+			}
+		}
+		if len(ft) > 0 {
+			h.enc.format = ft
+		}
+	}
+}
+
+// HandlerDelim sets the delimiter between
+// the elements of the records written by the handler
+func HandlerDelim(d string) HandlerOption {
+	return func(h *WriterHandler) { h.enc.delim = d }
+}
+
+// HandlerDateTimeFormat sets the datetime format
+// of the records written by the handler
+func HandlerDateTimeFormat(f string) HandlerOption {
+	return func(h *WriterHandler) { h.enc.timeFmt = f }
+}
+
+// Enabled evaluates whether records of
+// Level 'l' are written by the handler
+func (h *WriterHandler) Enabled(l Level) bool {
+	return l >= h.level
+}
+
+// Handle formats the record 'r' and
+// writes it to the writer of the handler
+func (h *WriterHandler) Handle(r *Record) error {
+	b, terr := h.enc.encode(r)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, err := h.w.Write(b); err != nil {
+		return err
+	}
+	return terr
+}
+
+// multiHandler is a Handler which fans
+// out records to each of its handlers
+type multiHandler []Handler
+
+// MultiHandler creates a handler which posts each
+// record to every handler provided that is
+// enabled for the level of the record
+func MultiHandler(hs ...Handler) Handler {
+	return multiHandler(hs)
+}
+
+// Enabled evaluates whether any of the handlers
+// handles records of Level 'l'
+func (m multiHandler) Enabled(l Level) bool {
+	for _, h := range m {
+		if h.Enabled(l) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle posts the record 'r' to each handler
+// enabled for its level and returns the
+// first error of the handlers, if any
+func (m multiHandler) Handle(r *Record) error {
+	var err error
+	for _, h := range m {
+		if h.Enabled(r.Level) {
+			if e := h.Handle(r); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// Flush flushes each handler with a Flush method and
+// returns the first error of the handlers, if any
+func (m multiHandler) Flush() error {
+	var err error
+	for _, h := range m {
+		if f, ok := h.(flusher); ok {
+			if e := f.Flush(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// Close closes each handler with a Close method and
+// returns the first error of the handlers, if any
+func (m multiHandler) Close() error {
+	var err error
+	for _, h := range m {
+		if c, ok := h.(io.Closer); ok {
+			if e := c.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// Dropped returns the count of records dropped by
+// the handlers which count the records they drop
+func (m multiHandler) Dropped() uint64 {
+	var n uint64
+	for _, h := range m {
+		if d, ok := h.(dropper); ok {
+			n += d.Dropped()
+		}
+	}
+	return n
+}
+
+// flusher is a Handler which buffers records
+// until they are flushed, such as HTTPHandler
+type flusher interface {
+	Flush() error
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// recordHandler is a handler which
+// retains the records it handles
+type recordHandler struct {
+	level   Level
+	records []Record
+}
+
+func (h *recordHandler) Enabled(l Level) bool {
+	return l >= h.level
+}
+
+func (h *recordHandler) Handle(r *Record) error {
+	h.records = append(h.records, *r)
+	return nil
+}
+
+func TestHandler(t *testing.T) {
+	h := &recordHandler{}
+	lg := New(WithHandler(h), WithSession("s1"), WithHost("h1"))
+	lg.With("request", "r1").Warning("slow", "ms", 12)
+	if len(h.records) != 1 {
+		t.Fatal("logger did not post record to handler")
+	}
+	r := h.records[0]
+	if r.Level != WARNING || r.Message != "slow" || r.Session != "s1" || r.Host != "h1" {
+		t.Fatalf("handler did not receive record elements: %+v", r)
+	}
+	if !strings.Contains(r.Source, "handler_test.go:") || r.Time.IsZero() {
+		t.Fatalf("handler did not receive record source and time: %+v", r)
+	}
+	if len(r.Fields) != 2 || r.Fields[0] != F("request", "r1") || r.Fields[1] != F("ms", 12) {
+		t.Fatalf("handler did not receive record fields: %+v", r.Fields)
+	}
+}
+
+func TestMultiHandler(t *testing.T) {
+	file, console, errs := new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer)
+	lg := New(WithSession("s1"), WithHandler(MultiHandler(
+		NewWriterHandler(file, HandlerLevel(INFO), HandlerFormat(LogJsonFmt, LogLevel, LogMessage)),
+		NewWriterHandler(console, HandlerFormat(LogLevel, LogMessage), HandlerDelim("|")),
+		NewWriterHandler(errs, HandlerLevel(ERROR), HandlerFormat(LogSession, LogMessage), HandlerDelim("|")),
+	)))
+	lg.Trace("t")
+	lg.Info("i", "k", "v")
+	lg.Error("e")
+	if r := file.String(); r != `{"k":"v","level":"INFO","message":"i"}`+"\n"+`{"level":"ERROR","message":"e"}`+"\n" {
+		t.Fatalf("json handler did not write records at its level: %q", r)
+	}
+	if r := console.String(); !strings.HasPrefix(r, "TRACE|t|stack=") || !strings.HasSuffix(r, "\nINFO|i|k=v\nERROR|e\n") {
+		t.Fatalf("std handler did not write records at its level: %q", r)
+	}
+	if r := errs.String(); r != "s1|e\n" {
+		t.Fatalf("error handler did not write records at its level: %q", r)
+	}
+	lg = New(WithHandler(MultiHandler(NewWriterHandler(file, HandlerLevel(ERROR)))))
+	if lg.Enabled(WARNING) || !lg.Enabled(ERROR) {
+		t.Fatal("logger was not enabled by the levels of its handlers")
+	}
+}
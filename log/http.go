@@ -0,0 +1,418 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BatchFormat encodes a batch of json
+// records to the body of a request
+type BatchFormat struct {
+	ContentType string                     // the content type of the body
+	Encode      func(recs [][]byte) []byte // encodes the json records to the body
+}
+
+var (
+	// NDJSON encodes a batch as newline delimited json records
+	NDJSON = BatchFormat{"application/x-ndjson", func(recs [][]byte) []byte {
+		return append(bytes.Join(recs, []byte("\n")), '\n')
+	}}
+	// JSONArray encodes a batch as a json array of records
+	JSONArray = BatchFormat{"application/json", func(recs [][]byte) []byte {
+		return append(append([]byte("["), bytes.Join(recs, []byte(","))...), ']')
+	}}
+)
+
+// httpEncoder is the format of the
+// records shipped by an HTTPHandler
+var httpEncoder = encoder{
+	format: []int{LogLevel, LogDateTime, LogSession, LogHost, LogService,
+		LogTraceID, LogSpanID, LogSource, LogMessage},
+	timeFmt: time.RFC3339Nano,
+	jsonFmt: true,
+}
+
+// errRejected is the error of a batch
+// rejected by the log collector
+var errRejected = errors.New("log collector rejected batch")
+
+// HTTPHandler is a Handler which ships batches of json
+// records to a log collector in HTTP POST requests.
+// Batches are posted when they reach the batch size or
+// at the batch interval, retried with backoff and spooled
+// to disk while the collector is unavailable
+type HTTPHandler struct {
+	url      string        // the endpoint of the log collector
+	client   *http.Client  // the client of the requests
+	header   http.Header   // the headers of the requests
+	format   BatchFormat   // the format of the batches
+	gzip     bool          // if true, the batches are gzipped
+	level    Level         // the minimum level shipped by the handler
+	size     int           // the records posted per batch
+	interval time.Duration // the interval of posting batches
+	retries  int           // the retries of a batch before it is spooled
+	backoff  time.Duration // the wait before the first retry, doubled per retry
+	spool    string        // the spool directory, if any
+	spoolMax int64         // the maximum bytes of the spool
+	buffer   int           // the max records held while batches are posted
+
+	mu      sync.Mutex    // guards batch and closed
+	batch   [][]byte      // the records not yet posted
+	closed  bool          // if true, records are no longer accepted
+	sendMu  sync.Mutex    // serializes posting and spooling batches
+	seq     int           // the sequence of the spool files
+	dropped uint64        // the count of records dropped
+	flush   chan struct{} // signals that the batch is full
+	done    chan struct{} // closed when the handler is closed
+	stopped chan struct{} // closed when the background goroutine exits
+}
+
+// HTTPOption configures an HTTPHandler
+// created with log.NewHTTPHandler
+type HTTPOption func(*HTTPHandler)
+
+// NewHTTPHandler creates a handler shipping records to the
+// log collector at 'url' in NDJSON batches of 100 records
+// posted at least every second, retried 3 times, holding
+// at most 10 batches of records, without a spool,
+// overidden by the options provided. The handler
+// posts batches in a background goroutine until Close
+func NewHTTPHandler(url string, opts ...HTTPOption) *HTTPHandler {
+	h := &HTTPHandler{
+		url:      url,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		header:   http.Header{},
+		format:   NDJSON,
+		size:     100,
+		interval: time.Second,
+		retries:  3,
+		backoff:  100 * time.Millisecond,
+		spoolMax: 64 << 20,
+		flush:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(h)
+	}
+	if h.size < 1 {
+		h.size = 1
+	}
+	if h.interval <= 0 {
+		h.interval = time.Second
+	}
+	if h.buffer < 1 {
+		h.buffer = 10 * h.size
+	}
+	go h.run()
+	return h
+}
+
+// HTTPFormat sets the format of the batches
+// posted by the handler, NDJSON by default
+func HTTPFormat(f BatchFormat) HTTPOption {
+	return func(h *HTTPHandler) { h.format = f }
+}
+
+// HTTPGzip compresses the batches
+// posted by the handler with gzip
+func HTTPGzip() HTTPOption {
+	return func(h *HTTPHandler) { h.gzip = true }
+}
+
+// HTTPBatch posts a batch when it reaches 'size'
+// records or at the 'interval', whichever is first
+func HTTPBatch(size int, interval time.Duration) HTTPOption {
+	return func(h *HTTPHandler) { h.size, h.interval = size, interval }
+}
+
+// HTTPRetry retries a failed batch 'n' times,
+// waiting 'backoff' before the first retry and
+// doubling the wait before each further retry
+func HTTPRetry(n int, backoff time.Duration) HTTPOption {
+	return func(h *HTTPHandler) { h.retries, h.backoff = n, backoff }
+}
+
+// HTTPSpool spools batches which could not be posted
+// to files in the directory 'dir' of at most 'max' bytes,
+// dropping the oldest batches when the spool is full.
+// Spooled batches are posted before new batches once
+// the collector is available, including those spooled
+// by a previous process
+func HTTPSpool(dir string, max int64) HTTPOption {
+	return func(h *HTTPHandler) { h.spool, h.spoolMax = dir, max }
+}
+
+// HTTPBuffer sets the max records held by the handler
+// while batches are posted, 10 batches by default.
+// Records beyond the buffer are dropped
+func HTTPBuffer(n int) HTTPOption {
+	return func(h *HTTPHandler) { h.buffer = n }
+}
+
+// HTTPClient sets the client of the
+// requests posted by the handler
+func HTTPClient(c *http.Client) HTTPOption {
+	return func(h *HTTPHandler) { h.client = c }
+}
+
+// HTTPHeader sets the header 'k' of the requests
+// posted by the handler, such as Authorization
+func HTTPHeader(k, v string) HTTPOption {
+	return func(h *HTTPHandler) { h.header.Set(k, v) }
+}
+
+// HTTPLevel sets the minimum level
+// of the records shipped by the handler
+func HTTPLevel(l Level) HTTPOption {
+	return func(h *HTTPHandler) { h.level = l }
+}
+
+// Enabled evaluates whether records of
+// Level 'l' are shipped by the handler
+func (h *HTTPHandler) Enabled(l Level) bool {
+	return l >= h.level
+}
+
+// Handle adds the record 'r' in json format to the
+// batch of the handler, signaling the batch to be
+// posted if it is full. The record is dropped if
+// the buffer of the handler is full
+func (h *HTTPHandler) Handle(r *Record) error {
+	b, _ := httpEncoder.encode(r) // json lines include the fields
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		atomic.AddUint64(&h.dropped, 1)
+		return errors.New("log handler closed")
+	}
+	if len(h.batch) >= h.buffer {
+		h.mu.Unlock()
+		atomic.AddUint64(&h.dropped, 1)
+		return nil
+	}
+	h.batch = append(h.batch, b)
+	full := len(h.batch) >= h.size
+	h.mu.Unlock()
+	if full {
+		select {
+		case h.flush <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush posts the spooled batches and the records
+// of the handler in batches of the batch size,
+// spooling the batches which could not be posted,
+// and returns the error of posting the batches, if any
+func (h *HTTPHandler) Flush() error {
+	h.sendMu.Lock()
+	defer h.sendMu.Unlock()
+	// records held are taken once the previous batches are posted
+	h.mu.Lock()
+	recs := h.batch
+	h.batch = nil
+	h.mu.Unlock()
+	err := h.drain()
+	for len(recs) > 0 {
+		batch := recs[:min(len(recs), h.size)]
+		recs = recs[len(batch):]
+		// batches are spooled once the collector is unavailable
+		e := err
+		if e == nil || errors.Is(e, errRejected) {
+			e = h.send(batch)
+		}
+		switch {
+		case e == nil:
+		case errors.Is(e, errRejected):
+			atomic.AddUint64(&h.dropped, uint64(len(batch)))
+		default:
+			if h.spoolBatch(batch) != nil {
+				atomic.AddUint64(&h.dropped, uint64(len(batch)))
+			}
+		}
+		if e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Close stops the handler and posts the
+// batch of the handler and the spooled batches
+func (h *HTTPHandler) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	h.mu.Unlock()
+	close(h.done)
+	<-h.stopped
+	return h.Flush()
+}
+
+// Dropped returns the count of records dropped
+// by the handler because they were rejected by the
+// collector or could not be spooled
+func (h *HTTPHandler) Dropped() uint64 {
+	return atomic.LoadUint64(&h.dropped)
+}
+
+// run posts the batch of the handler when
+// it is full or at the batch interval
+func (h *HTTPHandler) run() {
+	defer close(h.stopped)
+	t := time.NewTicker(h.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-h.flush:
+		case <-t.C:
+		}
+		h.Flush()
+	}
+}
+
+// send posts the batch 'recs' to the log collector,
+// retrying with backoff if the request fails or
+// the collector is unavailable
+func (h *HTTPHandler) send(recs [][]byte) error {
+	body := h.format.Encode(recs)
+	if h.gzip {
+		b := new(bytes.Buffer)
+		gz := gzip.NewWriter(b)
+		gz.Write(body)
+		gz.Close()
+		body = b.Bytes()
+	}
+	var err error
+	for i := 0; i <= h.retries; i++ {
+		if i > 0 {
+			time.Sleep(h.backoff << (i - 1))
+		}
+		if err = h.post(body); err == nil || errors.Is(err, errRejected) {
+			return err
+		}
+	}
+	return err
+}
+
+// post posts the request 'body' to the log collector
+func (h *HTTPHandler) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range h.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", h.format.ContentType)
+	if h.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	switch c := resp.StatusCode; {
+	case c >= 200 && c < 300:
+		return nil
+	case c >= 400 && c < 500 && c != http.StatusRequestTimeout && c != http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %s", errRejected, resp.Status)
+	}
+	return fmt.Errorf("log collector unavailable: %s", resp.Status)
+}
+
+// spoolBatch writes the batch 'recs' to a file in
+// the spool, removing the oldest spool files to keep
+// the spool within its maximum size. The spool is
+// only readable by the user, as records may hold
+// personal data
+func (h *HTTPHandler) spoolBatch(recs [][]byte) error {
+	if h.spool == "" {
+		return errors.New("log spool not configured")
+	}
+	b := append(bytes.Join(recs, []byte("\n")), '\n')
+	if int64(len(b)) > h.spoolMax {
+		return errors.New("log batch exceeds spool size")
+	}
+	if err := os.MkdirAll(h.spool, 0700); err != nil {
+		return err
+	}
+	files, size := h.spoolFiles()
+	for len(files) > 0 && size+int64(len(b)) > h.spoolMax {
+		if info, err := os.Stat(files[0]); err == nil {
+			size -= info.Size()
+			if old, err := os.ReadFile(files[0]); err == nil {
+				atomic.AddUint64(&h.dropped, uint64(bytes.Count(old, []byte("\n"))))
+			}
+		}
+		os.Remove(files[0])
+		files = files[1:]
+	}
+	h.seq++
+	name := filepath.Join(h.spool, fmt.Sprintf("%020d-%06d.ndjson", time.Now().UnixNano(), h.seq))
+	return os.WriteFile(name, b, 0600)
+}
+
+// drain posts the spooled batches in the order
+// they were spooled, removing each batch posted,
+// until a batch cannot be posted
+func (h *HTTPHandler) drain() error {
+	if h.spool == "" {
+		return nil
+	}
+	files, _ := h.spoolFiles()
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		recs := bytes.Split(bytes.TrimSuffix(b, []byte("\n")), []byte("\n"))
+		if err := h.send(recs); errors.Is(err, errRejected) {
+			atomic.AddUint64(&h.dropped, uint64(len(recs)))
+		} else if err != nil {
+			return err
+		}
+		os.Remove(f)
+	}
+	return nil
+}
+
+// spoolFiles returns the spool files in the
+// order they were spooled and their total size
+func (h *HTTPHandler) spoolFiles() ([]string, int64) {
+	files, _ := filepath.Glob(filepath.Join(h.spool, "*.ndjson"))
+	sort.Strings(files)
+	var size int64
+	for _, f := range files {
+		if info, err := os.Stat(f); err == nil {
+			size += info.Size()
+		}
+	}
+	return files, size
+}
@@ -0,0 +1,185 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector is a log collector which fails
+// its first requests and retains the records
+// of the batches it accepts
+type collector struct {
+	mu      sync.Mutex
+	fail    int        // the requests to fail
+	status  int        // the status of failed requests
+	batches [][]string // the messages of the accepted batches
+	types   []string   // the content types of the requests
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail > 0 {
+		c.fail--
+		w.WriteHeader(c.status)
+		return
+	}
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = gz
+	}
+	b, _ := io.ReadAll(body)
+	var msgs []string
+	for _, ln := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		m := map[string]any{}
+		if json.Unmarshal([]byte(ln), &m) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		msgs = append(msgs, m["message"].(string))
+	}
+	c.batches = append(c.batches, msgs)
+	c.types = append(c.types, r.Header.Get("Content-Type")+" "+r.Header.Get("X-Key"))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (c *collector) accepted() [][]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]string{}, c.batches...)
+}
+
+func TestHTTPHandler(t *testing.T) {
+	c := &collector{fail: 2, status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	h := NewHTTPHandler(srv.URL, HTTPGzip(), HTTPBatch(2, time.Hour), HTTPRetry(2, time.Millisecond), HTTPHeader("X-Key", "k1"))
+	lg := New(WithSession("s1"), WithHandler(h))
+	lg.Info("one")
+	lg.Info("two")
+	for i := 0; i < 500 && len(c.accepted()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	lg.Warning("three")
+	if err := lg.Close(); err != nil {
+		t.Fatal("could not close http handler:", err)
+	}
+	b := c.accepted()
+	if len(b) != 2 || strings.Join(b[0], ",") != "one,two" || strings.Join(b[1], ",") != "three" {
+		t.Fatalf("http handler did not post batches by size and on close: %v", b)
+	}
+	if c.types[0] != "application/x-ndjson k1" {
+		t.Fatalf("http handler did not post ndjson with headers: %v", c.types)
+	}
+	if err := h.Handle(&Record{Message: "late"}); err == nil || h.Dropped() != 1 {
+		t.Fatal("http handler accepted record after close")
+	}
+}
+
+func TestHTTPSpool(t *testing.T) {
+	c := &collector{fail: 100, status: http.StatusBadGateway}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	spool := filepath.Join(t.TempDir(), "spool")
+	h := NewHTTPHandler(srv.URL, HTTPBatch(10, time.Hour), HTTPRetry(1, time.Millisecond), HTTPSpool(spool, 200), HTTPFormat(JSONArray))
+	for _, m := range []string{"a", "b", "c", "d"} {
+		h.Handle(&Record{Message: m, Time: time.Now()})
+		if err := h.Flush(); err == nil {
+			t.Fatal("http handler did not fail to post batch to unavailable collector")
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(spool, "*.ndjson"))
+	if len(files) != 2 || h.Dropped() != 2 {
+		t.Fatalf("http handler did not bound the spool: %v dropped %d", files, h.Dropped())
+	}
+	if info, err := os.Stat(files[0]); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("http handler spooled records readable by others: %v", info.Mode())
+	}
+	c.mu.Lock()
+	c.fail = 0
+	c.mu.Unlock()
+	// records of the spool are posted by a new handler
+	h = NewHTTPHandler(srv.URL, HTTPSpool(spool, 200))
+	h.Handle(&Record{Message: "e"})
+	if err := h.Close(); err != nil {
+		t.Fatal("could not drain spool:", err)
+	}
+	if b := c.accepted(); len(b) != 3 || b[0][0] != "c" || b[1][0] != "d" || b[2][0] != "e" {
+		t.Fatalf("http handler did not post spooled batches in order: %v", b)
+	}
+	if files, _ := os.ReadDir(spool); len(files) != 0 {
+		t.Fatal("http handler did not remove posted spool files")
+	}
+}
+
+func TestHTTPRejected(t *testing.T) {
+	c := &collector{fail: 1, status: http.StatusBadRequest}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	h := NewHTTPHandler(srv.URL, HTTPBatch(10, time.Hour), HTTPSpool(t.TempDir(), 1<<20))
+	h.Handle(&Record{Message: "bad"})
+	if err := h.Flush(); err == nil || h.Dropped() != 1 {
+		t.Fatal("http handler did not drop batch rejected by collector")
+	}
+	h.Handle(&Record{Message: "good"})
+	h.Close()
+	if b := c.accepted(); len(b) != 1 || b[0][0] != "good" {
+		t.Fatalf("http handler retried rejected batch: %v", b)
+	}
+}
+
+func TestHTTPBuffer(t *testing.T) {
+	c := &collector{}
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		c.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	h := NewHTTPHandler(srv.URL, HTTPBatch(1, time.Hour), HTTPBuffer(2))
+	lg := New(WithHandler(h))
+	lg.Info("one")
+	// the first batch is posted while the others are held
+	for i := 0; i < 500; i++ {
+		h.mu.Lock()
+		n := len(h.batch)
+		h.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for _, m := range []string{"two", "three", "four"} {
+		lg.Info(m)
+	}
+	if h.Dropped() != 1 {
+		t.Fatalf("http handler did not drop records beyond its buffer: %d", h.Dropped())
+	}
+	if lg.flushWithin(20 * time.Millisecond) {
+		t.Fatal("logger flush did not time out while batch posted")
+	}
+	close(release)
+	lg.Close()
+	if b := c.accepted(); len(b) != 3 || b[1][0] != "two" || b[2][0] != "three" {
+		t.Fatalf("http handler did not post held records in batches: %v", b)
+	}
+}
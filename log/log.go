@@ -9,41 +9,203 @@
 //   log format - INFO: 2006-01-02 15:04:05.000 main.go:12 log message here
 //   log file location - '../logs/file.log'
 // with the ability to customize logging prior to first log record
+// Loggers may be configured from a JSON or YAML file and
+// GOSIMPLE_LOG_ env vars with LoadConfig and Configure,
+// and the file watched for changes with WatchConfig
+// Independent loggers can be created with log.New(opts...)
+// and the package level functions post to a default logger
 // If a log directory is not provided in os.Setenv("GO_UTILS_LOG_PATH")
 // or in SetLogDir, then a directory is created at '../logs'
+// Records below the minimum level in os.Setenv("GO_UTILS_LOG_LEVEL")
+// or in SetLevel are not posted to log
+// Key/value fields may be posted with a log record, such as
+//   log.Info("payment settled", "order", id, "amount", amt)
+// Loggers are safe for concurrent use and each record
+// is written to the log file and console in a single write
+// Structured records may be posted to a custom Handler
+// in place of the log writer with SetHandler
+// Records may be sent to a syslog server with NewSyslogHandler
+// Records may be shipped to a log collector with NewHTTPHandler
+// and personal data masked in records with SetRedactor
+// Records of the log/slog package may be posted to a logger
+// with NewSlogHandler and records of a logger to a
+// slog.Handler with NewSlogSink
+// Records may be written asynchronously with SetAsync
+// and are written by Flush and Close
+// Records may be posted in logfmt format with SetFormat(LogLogfmt)
+// or in a user-defined format with SetTemplate
+// Logs are parsed to typed records with Scan or to maps with Read
+// Errors are posted with their causes using log.Err(err) and
+// stack traces with TRACE records or the levels in SetStackTrace
+// Records of tight loops may be sampled per call site
+// with SetSampling and rate limited per level with SetRateLimit
+// Records are posted to the console in a human-friendly
+// colored format in the manner of SetConsoleMode
+// Operations may be timed with spans started with log.Start
+// and posted with their duration and outcome by Span.End
+// Panics are posted to log with defer log.Recover() or
+// in goroutines started with log.Go and then panic again
+// or exit in the manner of SetPanicMode
+// Records of tests may be captured in memory with the
+// log/logtest package in place of a log directory
+// Session ids are generated by SetSessionGenerator, such as
+// UUIDs or ULIDs, and described by a header record with SetSessionHeader
+// Counters of the records posted are returned by Stats
+// and hooks called with the records of a level by AddHook
 // Fatal functions call os.Exit(1) after posting to log
+// and flushing the log, waiting at most 5s for the flush
 
 package log
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"math/rand"
 	"os"
 	"path/filepath"
 	"runtime"
-	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// CONFIGS: settings for logging destination and format
-var (
-	__SESSION__    string                                                            // the unique id to the log session
-	__HOST__       string                                                            // the source host server from os.GetEnv("HOST")
-	__SERVICE__    string                                                            // the source servoce from os.GetEnv("SERVICE")
-	__WRITER__     io.Writer                                                         // the writer used to post to log
-	__DIR__        string                                                            // the directory path to log files
-	__FILE__       string                                                            // the name of the current session log file
-	__DELIM__      = " \t"                                                           // the delimeter between log line elements
-	__JSON_FMT__   bool                                                              // if true, post log line in json format
-	__TIME_FMT__   = `2006-01-02 15:04:05.000`                                       // the date format posted to log
-	__TO_CONSOLE__ = true                                                            // if true, post logs to console
-	__ACTIVE__     bool                                                              // if true, configs are locked
-	__FORMAT__     = []int{LogLevel, LogDateTime, LogSession, LogSource, LogMessage} // the format for a log line
-)
+// Logger is an instance of a logging configuration
+// with its own writer, format, level threshold,
+// host, service and session.
+// Its settings may be customized prior to its first log record
+type Logger struct {
+	*config         // the configs shared with derived loggers
+	fields  []Field // the context fields posted in every record
+}
+
+// config is the logging configuration of
+// a Logger and the loggers derived from it
+type config struct {
+	session      string           // the unique id to the log session
+	host         string           // the source host server from os.GetEnv("HOST")
+	service      string           // the source servoce from os.GetEnv("SERVICE")
+	writer       io.Writer        // the writer used to post to log
+	dir          string           // the directory path to log files
+	file         string           // the name of the current session log file
+	delim        string           // the delimeter between log line elements
+	jsonFmt      bool             // if true, post log line in json format
+	logfmt       bool             // if true, post log line in logfmt format
+	tmpl         *template        // the user-defined format of a log line, if any
+	timeFmt      string           // the date format posted to log
+	toConsole    bool             // if true, post logs to console
+	active       uint32           // if 1, configs are locked
+	mu           sync.Mutex       // guards the configs
+	format       []int            // the format for a log line
+	rotation     *Rotation        // the rotation of the log file, if any
+	asyncSize    int              // the buffer size of the async writer, 0 for sync writes
+	overflow     Overflow         // the behavior of the async writer when its buffer is full
+	async        *asyncWriter     // the async writer wrapping the writer, if any
+	console      io.Writer        // the console writer, if separate from the writer
+	consoleMode  ConsoleMode      // the format of the records posted to console
+	consoleAsync *asyncWriter     // the async writer wrapping the console, if any
+	closer       io.Closer        // the log file opened by the logger, if any
+	handler      Handler          // the handler of the records, defaults to the writer
+	level        uint32           // the minimum level posted to log
+	levelSet     bool             // if true, the level overides GO_UTILS_LOG_LEVEL
+	stack        uint32           // the minimum level posted with a stack trace
+	panicLevel   Level            // the level of the records of recovered panics
+	panicMode    PanicMode        // the behavior after posting a recovered panic
+	limiter      *limiter         // the sampling and rate limits of records, if any
+	redactor     *Redactor        // the redactor of records, if any
+	slow         int64            // the duration above which spans are posted as warnings
+	stats        counters         // the counters of the records posted
+	hooks        levelHooks       // the hooks of the records by level
+	onError      func(error)      // the handler of write errors, if any
+	ownHandler   bool             // if true, the handler writes through counted writers
+	sessionGen   SessionGenerator // the generator of the session id, if any
+	header       bool             // if true, a header record is posted at session start
+	version      string           // the binary version of the header record
+	tmplReported uint32           // if 1, a template omitting the fields of records was reported
+}
+
+// Option configures a Logger created with log.New
+type Option func(*Logger)
+
+// New creates a Logger using the default configs
+// overidden by the options provided.
+// The logger is activated on its first log record
+func New(opts ...Option) *Logger {
+	lg := &Logger{config: &config{
+		delim:      defaultEncoder.delim,
+		timeFmt:    defaultEncoder.timeFmt,
+		toConsole:  true,
+		format:     defaultEncoder.format,
+		stack:      uint32(noStack),
+		panicLevel: FATAL,
+	}}
+	for _, o := range opts {
+		o(lg)
+	}
+	return lg
+}
+
+// defaultEncoder is the default format of log records
+var defaultEncoder = encoder{
+	format:  []int{LogLevel, LogDateTime, LogSession, LogSource, LogMessage},
+	delim:   " \t",
+	timeFmt: `2006-01-02 15:04:05.000`,
+}
+
+// std is the default logger used by the
+// package level log functions, which is
+// replaced atomically by SetDefault
+var std atomic.Pointer[Logger]
+
+func init() {
+	std.Store(New())
+}
+
+// Default returns the default logger used by
+// the package level log functions
+func Default() *Logger {
+	return std.Load()
+}
+
+// SetDefault replaces the default logger used by
+// the package level log functions with 'lg' and
+// returns the previous default logger. Tests may
+// post records of the package level functions to a
+// new logger, which is unconfigured and inactive.
+// It is safe to call while records are posted, which
+// are posted to either the previous or new logger
+func SetDefault(lg *Logger) *Logger {
+	return std.Swap(lg)
+}
+
+// With returns a logger derived from the logger
+// which posts the key/value fields in 'kv' with
+// every record. The derived logger shares the
+// configs, writer and session of the logger
+func (lg *Logger) With(kv ...any) *Logger {
+	return &Logger{
+		config: lg.config,
+		fields: lg.withFields(kv),
+	}
+}
+
+// With returns a logger derived from the default
+// logger which posts the key/value fields in 'kv'
+// with every record
+func With(kv ...any) *Logger {
+	return Default().With(kv...)
+}
+
+// withFields returns the context fields of the
+// logger followed by the key/value fields in 'kv'
+func (lg *Logger) withFields(kv []any) []Field {
+	if len(kv) == 0 {
+		return lg.fields
+	}
+	n := len(lg.fields)
+	return append(lg.fields[:n:n], fieldsOf(kv)...)
+}
 
 // Format Elements: elements included in log record
 const (
@@ -57,9 +219,15 @@ const (
 	LogMessage           // post the log message to the log
 	LogJsonFmt           // post log line in json format
 	LogStdFmt            // post log line in delimited format
+	LogTraceID           // post the trace id from the context to the log
+	LogSpanID            // post the span id from the context to the log
+	LogLogfmt            // post log line in logfmt format
 )
 
-var elNames = []string{
+// logTmplFmt is the line format of a template
+const logTmplFmt = LogLogfmt + 1
+
+var elNames = map[int]string{
 	LogLevel:      "level",
 	LogDateTime:   "datetime",
 	LogFullSource: "fullsource",
@@ -68,6 +236,125 @@ var elNames = []string{
 	LogHost:       "host",
 	LogService:    "service",
 	LogMessage:    "message",
+	LogTraceID:    "trace",
+	LogSpanID:     "span",
+}
+
+// ElementByName returns the format element of
+// the name 'n', such as "level" or "trace" as named
+// in log files and configs, and whether it exists
+func ElementByName(n string) (int, bool) {
+	n = strings.ToLower(n)
+	for el, v := range elNames {
+		if v == n {
+			return el, true
+		}
+	}
+	return 0, false
+}
+
+// emptyEl is posted in place of an empty element
+// in the delimited format to keep the position
+// of the elements of a record
+const emptyEl = "-"
+
+// WithFormat sets the order and elements
+// of the log records of a new Logger
+func WithFormat(f ...int) Option {
+	return func(lg *Logger) { lg.SetFormat(f...) }
+}
+
+// WithDateTimeFormat sets the datetime format
+// of the log records of a new Logger
+func WithDateTimeFormat(f string) Option {
+	return func(lg *Logger) { lg.SetDateTimeFormat(f) }
+}
+
+// WithDelim sets the delimiter between the
+// log record elements of a new Logger
+func WithDelim(d string) Option {
+	return func(lg *Logger) { lg.SetDelim(d) }
+}
+
+// WithConsole controls whether a new Logger
+// writes its records to the console
+func WithConsole(c bool) Option {
+	return func(lg *Logger) { lg.LogToConsole(c) }
+}
+
+// WithDir sets the directory of the log
+// files of a new Logger
+func WithDir(d string) Option {
+	return func(lg *Logger) { lg.SetDir(d) }
+}
+
+// WithFile sets the name of the log
+// file of a new Logger
+func WithFile(f string) Option {
+	return func(lg *Logger) { lg.SetFile(f) }
+}
+
+// WithWriter sets a custom io.writer
+// as the writer of a new Logger
+func WithWriter(w io.Writer) Option {
+	return func(lg *Logger) { lg.SetWriter(w) }
+}
+
+// WithHost sets the host posted
+// in the log records of a new Logger
+func WithHost(h string) Option {
+	return func(lg *Logger) { lg.SetHost(h) }
+}
+
+// WithService sets the service posted
+// in the log records of a new Logger
+func WithService(s string) Option {
+	return func(lg *Logger) { lg.SetService(s) }
+}
+
+// WithSession sets the session id posted
+// in the log records of a new Logger
+func WithSession(s string) Option {
+	return func(lg *Logger) { lg.SetSession(s) }
+}
+
+// WithRotation sets the rotation of
+// the log files of a new Logger
+func WithRotation(r Rotation) Option {
+	return func(lg *Logger) { lg.SetRotation(r) }
+}
+
+// WithAsync writes the records of a new Logger
+// asynchronously through a buffer of 'size' records
+// using the Overflow 'o' when the buffer is full
+func WithAsync(size int, o Overflow) Option {
+	return func(lg *Logger) { lg.SetAsync(size, o) }
+}
+
+// WithHandler posts the records of a new
+// Logger to the handler 'h' provided
+func WithHandler(h Handler) Option {
+	return func(lg *Logger) { lg.SetHandler(h) }
+}
+
+// WithLevel sets the minimum level
+// posted to the log by a new Logger
+func WithLevel(l Level) Option {
+	return func(lg *Logger) { lg.SetLevel(l) }
+}
+
+// WithPanicMode sets the Level of the records of
+// panics recovered by a new Logger and the behavior
+// of the logger after posting a recovered panic
+func WithPanicMode(l Level, m PanicMode) Option {
+	return func(lg *Logger) { lg.SetPanicMode(l, m) }
+}
+
+// WithStackTrace posts the stack trace of the
+// log call with records of Level 'l' and above
+// posted by a new Logger
+func WithStackTrace(l Level) Option {
+	return func(lg *Logger) { lg.SetStackTrace(l) }
 }
 
 // SetFormat configures the order
@@ -75,95 +362,443 @@ var elNames = []string{
 // using the elements and their order provided
 // as arguments to the function.
 // Elements provied as log.Log<element>
-func SetFormat(f ...int) {
+func (lg *Logger) SetFormat(f ...int) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if lg.isActive() {
+		return
+	}
 	ft := []int{}
-	l := len(elNames)
 	for _, i := range f {
-		if l > i {
+		if _, ok := elNames[i]; ok {
 			ft = append(ft, i)
-		} else if i == LogJsonFmt {
-			__JSON_FMT__ = true
-		} else if i == LogStdFmt {
-			__JSON_FMT__ = false
+		} else if i == LogJsonFmt || i == LogStdFmt || i == LogLogfmt {
+			lg.jsonFmt, lg.logfmt, lg.tmpl = i == LogJsonFmt, i == LogLogfmt, nil
 		}
 	}
 	if len(ft) > 0 {
-		__FORMAT__ = ft
+		lg.format = ft
 	}
 }
 
 // SetDateTimeFormat sets the format of the
 // datetime stamp in the log record and
 // uses the same formats as the go time pkg
-func SetDateTimeFormat(f string) {
-	if !__ACTIVE__ {
+func (lg *Logger) SetDateTimeFormat(f string) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
 		_, err := time.Parse(string(f), string(f))
 		if err != nil {
 			panic("could not set log datetime format: invalid format")
 		}
-		__TIME_FMT__ = f
+		lg.timeFmt = f
 	}
 }
 
 // SetDelim sets the delimiter used to
 // separated log record elements
-func SetDelim(d string) {
-	if !__ACTIVE__ {
-		__DELIM__ = d
+func (lg *Logger) SetDelim(d string) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.delim = d
 	}
 }
 
 // LogToConsole controls whether logs are
 // written to the console during runtime
-func LogToConsole(c bool) {
-	if !__ACTIVE__ {
-		__TO_CONSOLE__ = c
+func (lg *Logger) LogToConsole(c bool) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.toConsole = c
 	}
 }
 
 // SetLogDir overides the env var GO_UTILS_LOG_PATH and
 // sets the location of the log files to the path provided
-func SetDir(d string) {
-	if !__ACTIVE__ {
+func (lg *Logger) SetDir(d string) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
 		if _, err := os.Stat(d); errors.Is(err, os.ErrNotExist) {
 			panic("could not set custom log dir: " + d)
 		}
-		__DIR__ = d
+		lg.dir = d
 	}
 }
 
 // SetLogFile overides the standard file naming and
 // sets the name of the log file in the log directory
-func SetFile(f string) {
-	if !__ACTIVE__ {
-		__FILE__ = f
+func (lg *Logger) SetFile(f string) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.file = f
 	}
 }
 
 // SetLogWriter overides the standard writer with
 // a custom provided io.writer
-func SetWriter(w io.Writer) {
-	if !__ACTIVE__ {
-		__WRITER__ = w
+func (lg *Logger) SetWriter(w io.Writer) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.writer = w
+	}
+}
+
+// SetRotation rotates the log file in the log
+// directory by size and time using the Rotation 'r'
+func (lg *Logger) SetRotation(r Rotation) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.rotation = &r
+	}
+}
+
+// SetAsync writes records asynchronously by enqueueing
+// them onto a buffer of 'size' records which is drained
+// to the writer by a background goroutine, using the
+// Overflow 'o' when the buffer is full
+func (lg *Logger) SetAsync(size int, o Overflow) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.asyncSize, lg.overflow = size, o
+	}
+}
+
+// Flush posts the summaries of the records
+// suppressed by sampling and rate limits, waits
+// until the records enqueued by an async logger
+// are written to the log and flushes a handler
+// which buffers records, such as HTTPHandler
+func (lg *Logger) Flush() {
+	lg.mu.Lock()
+	a, ca, h := lg.async, lg.consoleAsync, lg.handler
+	lg.mu.Unlock()
+	lg.postSuppressed()
+	if a != nil {
+		a.Flush()
+	}
+	if ca != nil {
+		ca.Flush()
+	}
+	if f, ok := h.(flusher); ok {
+		f.Flush()
+	}
+}
+
+// exitFlushTimeout is the max wait of the flush
+// of a logger before it exits after a fatal record
+const exitFlushTimeout = 5 * time.Second
+
+// flushWithin flushes the logger, waiting at most
+// 'd' for the flush, such as of a handler retrying
+// to post records to an unavailable log collector,
+// and returns whether the flush completed
+func (lg *Logger) flushWithin(d time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		lg.Flush()
+		close(done)
+	}()
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-done:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
+// postSuppressed posts the summaries of the records
+// suppressed by sampling and rate limits, if any
+func (lg *Logger) postSuppressed() {
+	lg.mu.Lock()
+	lm := lg.limiter
+	lg.mu.Unlock()
+	if lm == nil || !lg.isActive() {
+		return
+	}
+	for _, r := range lm.pending() {
+		lg.post(r)
+	}
+}
+
+// Close flushes the records enqueued by an
+// async logger, closes the log file opened by the
+// logger and closes a handler with a Close method,
+// such as HTTPHandler which then drains its spool.
+// Records posted after Close are lost
+func (lg *Logger) Close() error {
+	lg.postSuppressed()
+	lg.mu.Lock()
+	a, ca, c, h := lg.async, lg.consoleAsync, lg.closer, lg.handler
+	lg.closer = nil
+	lg.mu.Unlock()
+	if a != nil {
+		a.Close()
+	}
+	if ca != nil {
+		ca.Close()
+	}
+	var err error
+	if hc, ok := h.(io.Closer); ok {
+		err = hc.Close()
+	}
+	if c != nil {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Dropped returns the count of records
+// dropped by an async logger
+func (lg *Logger) Dropped() uint64 {
+	lg.mu.Lock()
+	a := lg.async
+	lg.mu.Unlock()
+	if a == nil {
+		return 0
+	}
+	return a.Dropped()
+}
+
+// SetHandler posts the structured records of the
+// logger to the handler 'h' provided in place of
+// writing the formatted records to the log writer
+func (lg *Logger) SetHandler(h Handler) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.handler = h
 	}
 }
 
 // SetHost overides the env var HOST and uses
 // the host provided in log posts
-func SetHost(h string) {
-	if !__ACTIVE__ {
-		__HOST__ = h
+func (lg *Logger) SetHost(h string) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.host = h
 	}
 }
 
 // SetService overides the env var SERVICE and uses
 // the service provided in log posts
-func SetService(s string) {
-	if !__ACTIVE__ {
-		__SERVICE__ = s
+func (lg *Logger) SetService(s string) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.service = s
+	}
+}
+
+// SetSession overides the generated session id
+// and uses the session provided in log posts
+func (lg *Logger) SetSession(s string) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.session = s
 	}
 }
 
+// SetLevel overides the env var GO_UTILS_LOG_LEVEL
+// and sets the minimum level of the records posted
+// to the log. Unlike other configs, the level
+// can be changed while the logger is active
+func (lg *Logger) SetLevel(l Level) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	atomic.StoreUint32(&lg.level, uint32(l))
+	lg.levelSet = true
+}
+
+// SetStackTrace posts the stack trace of the log
+// call with records of Level 'l' and above, such as
+// ERROR. TRACE records are always posted with the
+// stack trace. Like the level, it can be changed
+// while the logger is active
+func (lg *Logger) SetStackTrace(l Level) {
+	atomic.StoreUint32(&lg.stack, uint32(l))
+}
+
+// MinLevel returns the minimum level of
+// the records posted to the log
+func (lg *Logger) MinLevel() Level {
+	return Level(atomic.LoadUint32(&lg.level))
+}
+
+// Enabled evaluates whether a record of Level 'l'
+// is posted to the log by the logger and its handler.
+// It does not activate the logger, which is activated
+// by its first record posted
+func (lg *Logger) Enabled(l Level) bool {
+	if lg.isActive() {
+		return uint32(l) >= atomic.LoadUint32(&lg.level) && lg.handler.Enabled(l)
+	}
+	lg.mu.Lock()
+	min, h := Level(atomic.LoadUint32(&lg.level)), lg.handler
+	if !lg.levelSet {
+		if el, ok := envLevel(); ok {
+			min = el
+		}
+	}
+	lg.mu.Unlock()
+	// the handler of the writer of the logger posts all levels
+	return l >= min && (h == nil || h.Enabled(l))
+}
+
+// envLevel returns the minimum level
+// set in the env var GO_UTILS_LOG_LEVEL, if any
+func envLevel() (Level, bool) {
+	if v, exists := os.LookupEnv("GO_UTILS_LOG_LEVEL"); exists {
+		return levelByName(v)
+	}
+	return TRACE, false
+}
+
+// Session returns the session id of the logger
+func (lg *Logger) Session() string {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	return lg.session
+}
+
+// isActive evaluates whether the logger is
+// active and its configs are locked
+func (lg *Logger) isActive() bool {
+	return atomic.LoadUint32(&lg.active) == 1
+}
+
+// SetFormat configures the order
+// and elements of a log record
+// of the default logger
+func SetFormat(f ...int) {
+	Default().SetFormat(f...)
+}
+
+// SetDateTimeFormat sets the format of the
+// datetime stamp in the default logger
+func SetDateTimeFormat(f string) {
+	Default().SetDateTimeFormat(f)
+}
+
+// SetDelim sets the delimiter used to
+// separated log record elements
+// of the default logger
+func SetDelim(d string) {
+	Default().SetDelim(d)
+}
+
+// LogToConsole controls whether logs of the
+// default logger are written to the console
+func LogToConsole(c bool) {
+	Default().LogToConsole(c)
+}
+
+// SetLogDir overides the env var GO_UTILS_LOG_PATH and
+// sets the location of the default log files
+func SetDir(d string) {
+	Default().SetDir(d)
+}
+
+// SetLogFile sets the name of the
+// default log file in the log directory
+func SetFile(f string) {
+	Default().SetFile(f)
+}
+
+// SetLogWriter overides the writer of
+// the default logger
+func SetWriter(w io.Writer) {
+	Default().SetWriter(w)
+}
+
+// SetRotation rotates the default log file
+// by size and time using the Rotation 'r'
+func SetRotation(r Rotation) {
+	Default().SetRotation(r)
+}
+
+// SetAsync writes the records of the default
+// logger asynchronously through a buffer of 'size'
+// records using the Overflow 'o' when the buffer is full
+func SetAsync(size int, o Overflow) {
+	Default().SetAsync(size, o)
+}
+
+// Flush waits until the records enqueued by
+// the default logger are written to the log
+func Flush() {
+	Default().Flush()
+}
+
+// Close flushes the default logger
+// and closes its log file
+func Close() error {
+	return Default().Close()
+}
+
+// Dropped returns the count of records
+// dropped by the default logger
+func Dropped() uint64 {
+	return Default().Dropped()
+}
+
+// SetHandler posts the structured records of
+// the default logger to the handler 'h' provided
+func SetHandler(h Handler) {
+	Default().SetHandler(h)
+}
+
+// SetHost overides the env var HOST and uses
+// the host provided in default log posts
+func SetHost(h string) {
+	Default().SetHost(h)
+}
+
+// SetService overides the env var SERVICE and uses
+// the service provided in default log posts
+func SetService(s string) {
+	Default().SetService(s)
+}
+
+// SetSession overides the generated session id
+// of the default logger
+func SetSession(s string) {
+	Default().SetSession(s)
+}
+
+// SetLevel overides the env var GO_UTILS_LOG_LEVEL
+// and sets the minimum level of the records
+// posted to the default log during runtime
+func SetLevel(l Level) {
+	Default().SetLevel(l)
+}
+
+// SetStackTrace posts the stack trace of the log
+// call with records of Level 'l' and above
+// posted to the default log during runtime
+func SetStackTrace(l Level) {
+	Default().SetStackTrace(l)
+}
+
+// MinLevel returns the minimum level of
+// the records posted to the default log
+func MinLevel() Level {
+	return Default().MinLevel()
+}
+
 // LOG LEVELS: Level manages the logging levels
 type Level uint
 
@@ -175,6 +810,10 @@ const (
 	FATAL
 )
 
+// noStack is the stack trace level of a logger
+// posting stack traces only with TRACE records
+const noStack = FATAL + 1
+
 var levelNames = []string{
 	TRACE:   "TRACE",
 	INFO:    "INFO",
@@ -189,111 +828,279 @@ func (l Level) String() string {
 }
 
 // LevelByName returns logging Level for the provided string
+// and returns TRACE if the string is not a Level name
 func LevelByName(s string) Level {
-	s = strings.ToUpper(s)
-	var l Level
-	for _, v := range levelNames {
+	l, _ := levelByName(s)
+	return l
+}
+
+// levelByName returns logging Level for the provided string
+// and whether the string is a valid Level name
+func levelByName(s string) (Level, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	for l, v := range levelNames {
 		if v == s {
-			return l
+			return Level(l), true
 		}
 	}
-	return l
+	return TRACE, false
 }
 
 // Log records an entry to the log file
-// and prints to console if log.LogToConsole(true)
+// and prints to console if LogToConsole(true)
 // using the Level 'l' and 'msg' message provided
-func Log(l Level, msg string) {
-	dt := time.Now()
-	if !__ACTIVE__ {
-		activate()
-	}
-	_, fl, ln, _ := runtime.Caller(2)
-	fs := fmt.Sprint(fl, ":", ln)
-	logEls := map[string]string{
-		"level":      levelNames[l],
-		"datetime":   dt.Format(__TIME_FMT__),
-		"session":    __SESSION__,
-		"host":       __HOST__,
-		"service":    __SERVICE__,
-		"fullsource": fs,
-		"source":     fs[strings.LastIndex(fs, "/")+1:],
-		"message":    msg,
-	}
-	var r []byte
-	if __JSON_FMT__ {
-		r = buildJsonLog(logEls)
-	} else {
-		r = buildStdLog(logEls)
-	}
-	r = append(r, "\n"...)
-	__WRITER__.Write(r)
-}
-
-// buildStdLog is a helper function to Log
-// builds standard log format using elements in __FORMAT__
-// separated by the __DELIM__
-func buildStdLog(els map[string]string) []byte {
-	var log string
-	for i, el := range __FORMAT__ {
-		if v := els[elNames[el]]; v != "" {
-			if i > 0 {
-				log += __DELIM__
-			}
-			log += v
+// and the key/value fields in 'kv'
+func (lg *Logger) Log(l Level, msg string, kv ...any) {
+	lg.output(2, l, msg, kv...)
+}
+
+// output is a helper function to the Logger
+// log functions which posts the record
+// with the source of the caller 'depth'
+// levels above output in the call stack
+func (lg *Logger) output(depth int, l Level, msg string, kv ...any) {
+	lg.outputCtx(nil, depth+1, l, msg, kv...)
+}
+
+// outputCtx is a helper function to the Logger
+// log functions which posts the record with
+// the trace and span ids of the context 'ctx'
+// and the source of the caller 'depth'
+// levels above outputCtx in the call stack
+func (lg *Logger) outputCtx(ctx context.Context, depth int, l Level, msg string, kv ...any) {
+	if !lg.Enabled(l) {
+		return
+	}
+	r := &Record{
+		Level:   l,
+		Time:    time.Now(),
+		Message: msg,
+		Fields:  lg.withFields(kv),
+	}
+	_, fl, ln, _ := runtime.Caller(depth)
+	r.Source = fmt.Sprint(fl, ":", ln)
+	lg.emit(ctx, r, depth)
+}
+
+// emit samples the record 'r' of the caller 'depth'
+// levels above the caller of emit in the call stack,
+// captures its stack trace per the level of the record,
+// sets the trace and span ids of the context 'ctx'
+// and posts the record
+func (lg *Logger) emit(ctx context.Context, r *Record, depth int) {
+	l := r.Level
+	if lg.limiter != nil {
+		ok, sums := lg.limiter.allow(l, r.Source)
+		for _, s := range sums {
+			lg.post(s)
+		}
+		if !ok {
+			atomic.AddUint64(&lg.stats.suppressed, 1)
+			return
 		}
 	}
-	return []byte(log)
+	if l == TRACE || uint32(l) >= atomic.LoadUint32(&lg.stack) {
+		r.Stack = stack(depth + 1)
+	}
+	r.TraceID, r.SpanID = TraceFromContext(ctx)
+	lg.post(r)
 }
 
-// buildJsonLog is a helper function to Log
-// builds a json log format using the elements in __FORMAT__
-func buildJsonLog(els map[string]string) []byte {
-	log := map[string]string{}
-	for _, el := range __FORMAT__ {
-		if v := els[elNames[el]]; v != "" {
-			log[elNames[el]] = v
+// post activates the logger, if not active,
+// and posts the record 'r' to its handler
+func (lg *Logger) post(r *Record) {
+	if !lg.isActive() {
+		lg.activate()
+	}
+	lg.handle(r)
+}
+
+// handle stamps the record 'r' with the session,
+// host and service of the logger, masks it with
+// the redactor of the logger, if any, calls the
+// hooks of its level, counts it and posts it
+// to the handler of the logger
+func (lg *Logger) handle(r *Record) {
+	r.Session, r.Host, r.Service = lg.session, lg.host, lg.service
+	if lg.redactor != nil {
+		lg.redactor.Redact(r)
+	}
+	for _, h := range lg.hooks[r.Level] {
+		h(r)
+	}
+	atomic.AddUint64(&lg.stats.records[r.Level], 1)
+	err := lg.handler.Handle(r)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrTemplateFields):
+		// a template omitting the fields of records is reported once
+		if atomic.CompareAndSwapUint32(&lg.tmplReported, 0, 1) {
+			lg.writeError(err)
 		}
+	case !lg.ownHandler:
+		// errors of the writers of the logger are counted as written
+		lg.writeError(err)
 	}
-	r, _ := json.Marshal(log)
-	return r
+}
+
+// Trace is typically used for debugging
+// it records a TRACE emtry to the log file
+// and prints to console if LogToConsole(true)
+// using 'msg' message provided and the stack trace
+// of the call and the key/value fields in 'kv'
+func (lg *Logger) Trace(msg string, kv ...any) {
+	lg.output(2, TRACE, msg, kv...)
+}
+
+// Info records an INFO entry to the log file
+// and prints to console if LogToConsole(true)
+// using 'msg' message provided
+// and the key/value fields in 'kv'
+func (lg *Logger) Info(msg string, kv ...any) {
+	lg.output(2, INFO, msg, kv...)
+}
+
+// Warning records a WARNING entry to the log file
+// and prints to console if LogToConsole(true)
+// using 'msg' message provided
+// and the key/value fields in 'kv'
+func (lg *Logger) Warning(msg string, kv ...any) {
+	lg.output(2, WARNING, msg, kv...)
+}
+
+// Error  records an ERROR entry to the log file
+// and prints to console if LogToConsole(true)
+// using 'msg' message provided
+// and the key/value fields in 'kv'
+func (lg *Logger) Error(msg string, kv ...any) {
+	lg.output(2, ERROR, msg, kv...)
+}
+
+// Fatal records a FATAL entry to the log file
+// prints to console if LogToConsole(true)
+// using 'msg' message provided
+// and the key/value fields in 'kv'
+// and exits application using os.Exit(1)
+func (lg *Logger) Fatal(msg string, kv ...any) {
+	lg.output(2, FATAL, msg, kv...)
+	lg.flushWithin(exitFlushTimeout)
+	os.Exit(1)
+}
+
+// Logf records an entry to the log file
+// and prints to console if LogToConsole(true)
+// using the Level 'l' and 'msg' message provided.
+// Arguments are handled in the manner of fmt.Printf
+func (lg *Logger) Logf(l Level, format string, a ...any) {
+	if lg.Enabled(l) {
+		lg.output(2, l, fmt.Sprintf(format, a...))
+	}
+}
+
+// Tracef is typically used for debugging
+// it records a TRACE emtry to the log file
+// and prints to console if LogToConsole(true)
+// using 'msg' message provided and the stack trace of the call.
+// Arguments are handled in the manner of fmt.Printf
+func (lg *Logger) Tracef(format string, a ...any) {
+	if lg.Enabled(TRACE) {
+		lg.output(2, TRACE, fmt.Sprintf(format, a...))
+	}
+}
+
+// Infof records an INFO entry to the log file
+// and prints to console if LogToConsole(true)
+// using 'msg' message provided.
+// Arguments are handled in the manner of fmt.Printf
+func (lg *Logger) Infof(format string, a ...any) {
+	if lg.Enabled(INFO) {
+		lg.output(2, INFO, fmt.Sprintf(format, a...))
+	}
+}
+
+// Warningf records a WARNING entry to the log file
+// and prints to console if LogToConsole(true)
+// using 'msg' message provided.
+// Arguments are handled in the manner of fmt.Printf
+func (lg *Logger) Warningf(format string, a ...any) {
+	if lg.Enabled(WARNING) {
+		lg.output(2, WARNING, fmt.Sprintf(format, a...))
+	}
+}
+
+// Error  records an ERROR entry to the log file
+// and prints to console if LogToConsole(true)
+// using 'msg' message provided.
+// Arguments are handled in the manner of fmt.Printf
+func (lg *Logger) Errorf(format string, a ...any) {
+	if lg.Enabled(ERROR) {
+		lg.output(2, ERROR, fmt.Sprintf(format, a...))
+	}
+}
+
+// Fatalf records a FATAL entry to the log file
+// prints to console if LogToConsole(true)
+// using 'msg' message provided
+// and exits application using os.Exit(1).
+// Arguments are handled in the manner of fmt.Printf
+func (lg *Logger) Fatalf(format string, a ...any) {
+	if lg.Enabled(FATAL) {
+		lg.output(2, FATAL, fmt.Sprintf(format, a...))
+	}
+	lg.flushWithin(exitFlushTimeout)
+	os.Exit(1)
+}
+
+// Log records an entry to the log file
+// and prints to console if log.LogToConsole(true)
+// using the Level 'l' and 'msg' message provided
+// and the key/value fields in 'kv'
+func Log(l Level, msg string, kv ...any) {
+	Default().output(2, l, msg, kv...)
 }
 
 // Trace is typically used for debugging
 // it records a TRACE emtry to the log file
 // and prints to console if log.LogToConsole(true)
-// using 'msg' message provided and the stacktrace
-func Trace(msg string) {
-	Log(TRACE, msg)
+// using 'msg' message provided and the stack trace
+// of the call and the key/value fields in 'kv'
+func Trace(msg string, kv ...any) {
+	Default().output(2, TRACE, msg, kv...)
 }
 
 // Info records an INFO entry to the log file
 // and prints to console if log.LogToConsole(true)
 // using 'msg' message provided
-func Info(msg string) {
-	Log(INFO, msg)
+// and the key/value fields in 'kv'
+func Info(msg string, kv ...any) {
+	Default().output(2, INFO, msg, kv...)
 }
 
 // Warning records a WARNING entry to the log file
 // and prints to console if log.LogToConsole(true)
 // using 'msg' message provided
-func Warning(msg string) {
-	Log(WARNING, msg)
+// and the key/value fields in 'kv'
+func Warning(msg string, kv ...any) {
+	Default().output(2, WARNING, msg, kv...)
 }
 
 // Error  records an ERROR entry to the log file
 // and prints to console if log.LogToConsole(true)
 // using 'msg' message provided
-func Error(msg string) {
-	Log(ERROR, msg)
+// and the key/value fields in 'kv'
+func Error(msg string, kv ...any) {
+	Default().output(2, ERROR, msg, kv...)
 }
 
 // Fatal records a FATAL entry to the log file
 // prints to console if log.LogToConsole(true)
 // using 'msg' message provided
+// and the key/value fields in 'kv'
 // and exits application using os.Exit(1)
-func Fatal(msg string) {
-	Log(FATAL, msg)
+func Fatal(msg string, kv ...any) {
+	lg := Default()
+	lg.output(2, FATAL, msg, kv...)
+	lg.flushWithin(exitFlushTimeout)
 	os.Exit(1)
 }
 
@@ -302,16 +1109,20 @@ func Fatal(msg string) {
 // using the Level 'l' and 'msg' message provided.
 // Arguments are handled in the manner of fmt.Printf
 func Logf(l Level, format string, a ...any) {
-	Log(l, fmt.Sprintf(format, a...))
+	if lg := Default(); lg.Enabled(l) {
+		lg.output(2, l, fmt.Sprintf(format, a...))
+	}
 }
 
 // Tracef is typically used for debugging
 // it records a TRACE emtry to the log file
 // and prints to console if log.LogToConsole(true)
-// using 'msg' message provided and the stacktrace.
+// using 'msg' message provided and the stack trace of the call.
 // Arguments are handled in the manner of fmt.Printf
 func Tracef(format string, a ...any) {
-	Logf(TRACE, format, a...)
+	if lg := Default(); lg.Enabled(TRACE) {
+		lg.output(2, TRACE, fmt.Sprintf(format, a...))
+	}
 }
 
 // Infof records an INFO entry to the log file
@@ -319,7 +1130,9 @@ func Tracef(format string, a ...any) {
 // using 'msg' message provided.
 // Arguments are handled in the manner of fmt.Printf
 func Infof(format string, a ...any) {
-	Logf(INFO, format, a...)
+	if lg := Default(); lg.Enabled(INFO) {
+		lg.output(2, INFO, fmt.Sprintf(format, a...))
+	}
 }
 
 // Warningf records a WARNING entry to the log file
@@ -327,7 +1140,9 @@ func Infof(format string, a ...any) {
 // using 'msg' message provided.
 // Arguments are handled in the manner of fmt.Printf
 func Warningf(format string, a ...any) {
-	Logf(WARNING, format, a...)
+	if lg := Default(); lg.Enabled(WARNING) {
+		lg.output(2, WARNING, fmt.Sprintf(format, a...))
+	}
 }
 
 // Error  records an ERROR entry to the log file
@@ -335,7 +1150,9 @@ func Warningf(format string, a ...any) {
 // using 'msg' message provided.
 // Arguments are handled in the manner of fmt.Printf
 func Errorf(format string, a ...any) {
-	Logf(ERROR, format, a...)
+	if lg := Default(); lg.Enabled(ERROR) {
+		lg.output(2, ERROR, fmt.Sprintf(format, a...))
+	}
 }
 
 // Fatalf records a FATAL entry to the log file
@@ -344,130 +1161,180 @@ func Errorf(format string, a ...any) {
 // and exits application using os.Exit(1).
 // Arguments are handled in the manner of fmt.Printf
 func Fatalf(format string, a ...any) {
-	Logf(FATAL, format, a...)
+	lg := Default()
+	if lg.Enabled(FATAL) {
+		lg.output(2, FATAL, fmt.Sprintf(format, a...))
+	}
+	lg.flushWithin(exitFlushTimeout)
 	os.Exit(1)
 }
 
-// Read parses the active log file to a map
-// and returns it for log evaluation
-func Read() []map[string]any {
-	m := []map[string]any{}
-	cnt, err := os.ReadFile(__DIR__ + "/" + __FILE__)
+// Read parses the active log file to maps of the
+// elements and fields of its records, with the
+// source as 'file' and 'line', for log evaluation
+func (lg *Logger) Read() ([]map[string]any, error) {
+	lg.mu.Lock()
+	path, enc := filepath.Join(lg.dir, lg.file), lg.encoder()
+	lg.mu.Unlock()
+	f, err := os.Open(path)
 	if err != nil {
-		panic("could not read log file")
-	}
-	for _, ln := range strings.Split(string(cnt), "\n") {
-		if len(ln) > 0 {
-			l := map[string]any{}
-			if __JSON_FMT__ {
-				json.Unmarshal([]byte(ln), &l)
-				m = append(m, l)
-			} else {
-				lVals := strings.Split(ln, __DELIM__)
-				for i, el := range __FORMAT__ {
-					if el == LogDateTime {
-						v, _ := time.Parse(__TIME_FMT__, lVals[i])
-						l[elNames[el]] = v
-					} else if el == LogSource || el == LogFullSource {
-						s := strings.Split(lVals[i], ":")
-						l["file"] = s[0]
-						l["line"], _ = strconv.Atoi(s[1])
-					} else {
-						l[elNames[el]] = lVals[i]
-					}
-				}
-				m = append(m, l)
-			}
-		}
+		return nil, err
 	}
-	return m
+	defer f.Close()
+	return readAll(f, enc)
+}
+
+// Read parses the active default log file
+// to maps for log evaluation
+func Read() ([]map[string]any, error) {
+	return Default().Read()
 }
 
 // Activates logging session by
 // by setting the session id, host, and service
 // configuring the log writer and
-// setting the __ACTIVE__ config to true
-func activate() {
-	if __SESSION__ == "" {
-		initSession()
+// setting the logger active config to true,
+// then posts the session header, if any.
+// Only the first of concurrent calls activates the logger
+func (lg *Logger) activate() {
+	if lg.setup() {
+		lg.postHeader()
 	}
-	if __HOST__ == "" {
+}
+
+// setup configures an inactive logger and sets it
+// active, returning false if the logger was active
+func (lg *Logger) setup() bool {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if lg.isActive() {
+		return false
+	}
+	if lg.session == "" {
+		lg.initSession()
+	}
+	if lg.host == "" {
 		h, exists := os.LookupEnv("HOST")
 		if exists {
-			__HOST__ = h
+			lg.host = h
 		}
 	}
-	if __SERVICE__ == "" {
+	if lg.service == "" {
 		s, exists := os.LookupEnv("SERVICE")
 		if exists {
-			__SERVICE__ = s
+			lg.service = s
+		}
+	}
+	if !lg.levelSet {
+		if l, ok := envLevel(); ok {
+			atomic.StoreUint32(&lg.level, uint32(l))
+		}
+	}
+	if lg.handler != nil {
+		atomic.StoreUint32(&lg.active, 1)
+		return true
+	}
+	if lg.writer == nil {
+		lg.initWriter()
+	}
+	lg.ownHandler = true
+	lg.writer = &countWriter{lg.writer, lg}
+	if lg.asyncSize > 0 {
+		lg.async = newAsyncWriter(lg.writer, lg.asyncSize, lg.overflow)
+		lg.writer = lg.async
+	}
+	lg.handler = &WriterHandler{w: lg.writer, enc: lg.encoder()}
+	if lg.console != nil {
+		var c io.Writer = &countWriter{lg.console, lg}
+		if lg.asyncSize > 0 {
+			lg.consoleAsync = newAsyncWriter(c, lg.asyncSize, lg.overflow)
+			c = lg.consoleAsync
+		}
+		if lg.consoleMode == ConsoleRaw {
+			lg.handler = MultiHandler(lg.handler, &WriterHandler{w: c, enc: lg.encoder()})
+		} else {
+			// detect the terminal of the console, not of its async writer
+			ch := NewConsoleHandler(lg.console, lg.consoleMode)
+			ch.w = c
+			lg.handler = MultiHandler(lg.handler, ch)
 		}
 	}
-	if __WRITER__ == nil {
-		initWriter()
+	atomic.StoreUint32(&lg.active, 1)
+	return true
+}
+
+// encoder returns the encoder of the
+// log format configured for the logger
+func (lg *Logger) encoder() encoder {
+	return encoder{
+		format:  lg.format,
+		delim:   lg.delim,
+		timeFmt: lg.timeFmt,
+		jsonFmt: lg.jsonFmt,
+		logfmt:  lg.logfmt,
+		tmpl:    lg.tmpl,
 	}
-	__ACTIVE__ = true
 }
 
 // generate and set the session id
-func initSession() {
-	__SESSION__ = time.Now().Format(`060102-150405`) + "-" + rGen(6)
-}
-
-func rGen(l int) string {
-	b := make([]byte, l)
-	rand.Seed(time.Now().UnixNano())
-	for i := range b {
-		n := rand.Intn(62)
-		if n > 35 {
-			n += 13
-		} else if n > 9 {
-			n += 7
-		}
-		b[i] = byte(48 + n)
+// using the session generator of the logger
+func (lg *Logger) initSession() {
+	gen := lg.sessionGen
+	if gen == nil {
+		gen = SessionTime
 	}
-	return string(b)
+	lg.session = gen()
 }
 
-// initWriter returns the writer for the log
+// initWriter sets the writer for the log
 // printing to both a log file and the console
 // panics if it cannot access or create a log file
-func initWriter() {
+func (lg *Logger) initWriter() {
 	var exists bool
 	var err error
-	if __DIR__ == "" { // set dir if not alread set
-		__DIR__, exists = os.LookupEnv("GO_UTILS_LOG_PATH")
+	if lg.dir == "" { // set dir if not alread set
+		lg.dir, exists = os.LookupEnv("GO_UTILS_LOG_PATH")
 		if !exists { // if env var not set, use default dir
-			__DIR__, err = filepath.Abs("../logs")
+			lg.dir, err = filepath.Abs("../logs")
 			if err != nil {
 				panic("cannot initialize logger path")
 			}
-			os.Setenv("GO_UTILS_LOG_PATH", __DIR__)
+			os.Setenv("GO_UTILS_LOG_PATH", lg.dir)
 		}
 	}
-	if _, err := os.Stat(__DIR__); errors.Is(err, os.ErrNotExist) {
+	if _, err := os.Stat(lg.dir); errors.Is(err, os.ErrNotExist) {
 		//create dir if it dones not already exist
-		err := os.Mkdir(__DIR__, os.ModePerm)
+		err := os.Mkdir(lg.dir, os.ModePerm)
 		if err != nil {
-			panic("could not initialize log directory: " + __DIR__)
+			panic("could not initialize log directory: " + lg.dir)
 		}
 	}
-	if __FILE__ == "" { // set default file name if not already set
-		__FILE__ = __SESSION__ + ".log"
+	if lg.file == "" { // set default file name if not already set
+		lg.file = lg.session + ".log"
 	}
 	s := "/"
-	if string(__FILE__[0]) == "/" {
+	if string(lg.file[0]) == "/" {
 		s = ""
 	}
-	if __WRITER__ == nil { // generate io writer if not already set
-		file, err := os.OpenFile(__DIR__+s+__FILE__, os.O_RDWR|os.O_CREATE, os.ModePerm)
+	if lg.writer == nil { // generate io writer if not already set
+		var file io.Writer
+		var err error
+		if lg.rotation != nil {
+			r := *lg.rotation
+			if r.OnError == nil {
+				r.OnError = lg.writeError
+			}
+			file, err = NewRotateWriter(lg.dir+s+lg.file, r)
+		} else {
+			file, err = os.OpenFile(lg.dir+s+lg.file, os.O_RDWR|os.O_CREATE, os.ModePerm)
+		}
 		if err != nil {
 			panic("could not initatiate log file")
 		}
-		if __TO_CONSOLE__ {
-			__WRITER__ = io.MultiWriter(os.Stdout, file)
-		} else {
-			__WRITER__ = io.Writer(file)
+		lg.closer = file.(io.Closer)
+		lg.writer = file
+		if lg.toConsole && lg.console == nil {
+			lg.console = os.Stdout
 		}
 	}
 }
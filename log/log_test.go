@@ -8,9 +8,9 @@ package log
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
-	"path/filepath"
+	"net/url"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
@@ -19,92 +19,133 @@ var (
 	format    = []int{LogHost, LogService, LogSession, LogSource, LogDateTime, LogLevel, LogMessage}
 	fdatetime = `20060102 150405`
 	delim     = "|"
-	dir, dErr = filepath.Abs("../../logs")
 	file      = "/test.log"
-	buffer    = new(bytes.Buffer)
 	host      = "test-server"
 	service   = "test-service"
 )
 
-func deactivate() {
-	__ACTIVE__ = false
+// configure replaces the default logger with a new
+// logger for the test 't', configured by the package
+// level functions to post to the buffer returned and
+// a log file in a temporary directory 'dir'
+func configure(t *testing.T) (buffer *bytes.Buffer, dir string) {
+	prev := SetDefault(New())
+	t.Cleanup(func() {
+		Default().Close()
+		SetDefault(prev)
+	})
+	buffer, dir = new(bytes.Buffer), t.TempDir()
+	SetFormat(append([]int{LogJsonFmt}, format...)...)
+	SetDateTimeFormat(fdatetime)
+	SetDelim(delim)
+	LogToConsole(false)
+	SetDir(dir)
+	SetFile(file)
+	SetWriter(buffer)
+	SetHost(host)
+	SetService(service)
+	return
 }
 
 func TestFormat(t *testing.T) {
-	f := append([]int{LogJsonFmt}, format...)
-	SetFormat(f...)
-	if !reflect.DeepEqual(__FORMAT__, format) {
-		t.Fatal("SetFormat did not update Log __FORMAT__")
+	_, dir := configure(t)
+	if !reflect.DeepEqual(Default().format, format) {
+		t.Fatal("SetFormat did not update logger format")
 	}
-	if !__JSON_FMT__ {
-		t.Fatal("SetFormat did not update Log __JSON_FMT__")
+	if !Default().jsonFmt {
+		t.Fatal("SetFormat did not update logger jsonFmt")
 	}
-	SetDateTimeFormat(fdatetime)
-	if __TIME_FMT__ != fdatetime {
-		t.Fatal("SetDateTimeFormat did not update Log __TIME_FMT__")
+	if Default().timeFmt != fdatetime {
+		t.Fatal("SetDateTimeFormat did not update logger timeFmt")
 	}
-	SetDelim(delim)
-	if __DELIM__ != delim {
-		t.Fatal("SetDelim did not update Log __DELIM__")
+	if Default().delim != delim {
+		t.Fatal("SetDelim did not update logger delim")
 	}
-	LogToConsole(false)
-	if __TO_CONSOLE__ {
-		t.Fatal("LogToConsole did not update Log __TO_CONSOLE__")
+	if Default().toConsole {
+		t.Fatal("LogToConsole did not update logger toConsole")
 	}
-	SetDir(dir)
-	if dErr != nil || __DIR__ != dir {
-		t.Fatal("SetDir did not update Log __DIR__")
+	if Default().dir != dir {
+		t.Fatal("SetDir did not update logger dir")
 	}
-	SetFile(file)
-	if __FILE__ != file {
-		t.Fatal("SetFile did not update Log __FILE__")
+	if Default().file != file {
+		t.Fatal("SetFile did not update logger file")
 	}
-	SetWriter(buffer)
-	if __WRITER__ == nil {
-		t.Fatal("SetWriter did not update Log __WRITER__")
+	if Default().writer == nil {
+		t.Fatal("SetWriter did not update logger writer")
 	}
-	SetHost(host)
-	if __HOST__ != host {
-		t.Fatal("SetHost did not update Log __HOST__")
+	if Default().host != host {
+		t.Fatal("SetHost did not update logger host")
 	}
-	SetService(service)
-	if __SERVICE__ != service {
-		t.Fatal("SetService did not update Log __SERVICE__")
+	if Default().service != service {
+		t.Fatal("SetService did not update logger service")
 	}
 }
 
 func TestActivate(t *testing.T) {
-	activate()
-	if !__ACTIVE__ {
+	_, dir := configure(t)
+	Default().activate()
+	if !Default().isActive() {
 		t.Fatal("Log was not activated on activate()")
 	}
-	if __SESSION__ == "" {
+	if Default().session == "" {
 		t.Fatal("Session was not set on activate()")
 	}
-	if __DIR__ != dir {
-		t.Fatal("Activate overrode preconfigured __DIR__")
+	if Default().dir != dir {
+		t.Fatal("Activate overrode preconfigured dir")
+	}
+	if Default().file != file {
+		t.Fatal("Activate overrode preconfigured file")
 	}
-	if __FILE__ != file {
-		t.Fatal("Activate overrode preconfigured __FILE__")
+	SetFormat(LogMessage)
+	if !Default().jsonFmt {
+		t.Fatal("SetFormat updated configs of active logger")
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	buffer, _ := configure(t)
+	lg := New(WithWriter(new(bytes.Buffer)))
+	prev := SetDefault(lg)
+	if Default() != lg {
+		t.Fatal("SetDefault did not replace default logger")
+	}
+	Info("not posted to previous default")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				Info("posted while replaced")
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		SetDefault(New(WithWriter(new(bytes.Buffer))))
+	}
+	wg.Wait()
+	SetDefault(prev)
+	if buffer.Len() > 0 {
+		t.Fatal("package level functions posted to previous default logger")
 	}
 }
 
 func TestLog(t *testing.T) {
+	buffer, _ := configure(t)
 	Logf(INFO, "test log Level: %s", levelNames[INFO])
 	m := map[string]string{}
 	err := json.Unmarshal(buffer.Bytes(), &m)
 	if err != nil {
 		t.Fatal("could not unmarshal json log")
 	}
-	fmt.Println(buffer)
 	if v, ok := m["host"]; !ok || v != host {
-		t.Fatal("log post host does not match __HOST__")
+		t.Fatal("log post host does not match logger host")
 	}
 	if v, ok := m["service"]; !ok || v != service {
-		t.Fatal("log post service does not match __SERVICE__")
+		t.Fatal("log post service does not match logger service")
 	}
-	if v, ok := m["session"]; !ok || v != __SESSION__ {
-		t.Fatal("log post session does not match __SESSION__")
+	if v, ok := m["session"]; !ok || v != Default().session {
+		t.Fatal("log post session does not match logger session")
 	}
 	if v, ok := m["level"]; !ok || v != levelNames[INFO] {
 		t.Fatal("log post level does not match level provided")
@@ -115,9 +156,218 @@ func TestLog(t *testing.T) {
 	if v, ok := m["datetime"]; !ok {
 		t.Fatal("log post datetime is missing")
 	} else if _, err := time.Parse(fdatetime, v); err != nil {
-		t.Fatal("log post datetime does not match __TIME_FMT__")
+		t.Fatal("log post datetime does not match logger timeFmt")
 	}
 	if v, ok := m["message"]; !ok || v != "test log Level: INFO" {
 		t.Fatal("log post message does not match the message provided")
 	}
 }
+
+func TestNew(t *testing.T) {
+	b1, b2 := new(bytes.Buffer), new(bytes.Buffer)
+	l1 := New(WithWriter(b1), WithFormat(LogJsonFmt, LogLevel, LogMessage), WithSession("s1"))
+	l2 := New(WithWriter(b2), WithDelim("|"), WithFormat(LogSession, LogLevel, LogMessage), WithSession("s2"))
+	l1.Info("first")
+	l2.Warning("second")
+	m := map[string]string{}
+	if err := json.Unmarshal(b1.Bytes(), &m); err != nil {
+		t.Fatal("could not unmarshal json log of new logger")
+	}
+	if m["level"] != "INFO" || m["message"] != "first" {
+		t.Fatal("new logger did not post record using its own format")
+	}
+	if r := b2.String(); r != "s2|WARNING|second\n" {
+		t.Fatalf("new logger did not post record using its own format: %q", r)
+	}
+	if Default().session == "s1" || Default().session == "s2" {
+		t.Fatal("new logger overrode default logger session")
+	}
+}
+
+type countStringer struct{ n *int }
+
+func (c countStringer) String() string {
+	*c.n++
+	return "counted"
+}
+
+func TestLevel(t *testing.T) {
+	b := new(bytes.Buffer)
+	lg := New(WithWriter(b), WithFormat(LogLevel, LogMessage), WithLevel(INFO))
+	lg.Trace("hidden")
+	if lg.Enabled(TRACE) || !lg.Enabled(INFO) || lg.isActive() {
+		t.Fatal("logger level check or filtered record activated the logger")
+	}
+	lg.Info("shown")
+	if r := b.String(); r != "INFO \tshown\n" {
+		t.Fatalf("logger did not filter records below minimum level: %q", r)
+	}
+	b.Reset()
+	lg.SetLevel(ERROR)
+	if lg.MinLevel() != ERROR {
+		t.Fatal("SetLevel did not update level of active logger")
+	}
+	n := 0
+	lg.Warningf("hidden %s", countStringer{&n})
+	if b.Len() > 0 || n > 0 {
+		t.Fatal("logger formatted or posted record below minimum level")
+	}
+	lg.Errorf("shown %s", countStringer{&n})
+	if r := b.String(); r != "ERROR \tshown counted\n" {
+		t.Fatalf("logger did not post record at minimum level: %q", r)
+	}
+}
+
+func TestLevelEnv(t *testing.T) {
+	t.Setenv("GO_UTILS_LOG_LEVEL", "warning")
+	b := new(bytes.Buffer)
+	lg := New(WithWriter(b), WithFormat(LogMessage))
+	if lg.Enabled(INFO) {
+		t.Fatal("inactive logger did not use GO_UTILS_LOG_LEVEL as minimum level")
+	}
+	lg.Info("hidden")
+	lg.Warning("shown")
+	if r := b.String(); r != "shown\n" {
+		t.Fatalf("logger did not use GO_UTILS_LOG_LEVEL as minimum level: %q", r)
+	}
+	if LevelByName("Error") != ERROR {
+		t.Fatal("LevelByName did not return level for name provided")
+	}
+	if el, ok := ElementByName("Trace"); !ok || el != LogTraceID {
+		t.Fatal("ElementByName did not return element for name provided")
+	}
+	if _, ok := ElementByName("fields"); ok {
+		t.Fatal("ElementByName returned element for unknown name")
+	}
+}
+
+func TestFields(t *testing.T) {
+	b := new(bytes.Buffer)
+	lg := New(WithWriter(b), WithFormat(LogJsonFmt, LogLevel, LogMessage))
+	lg.Info("payment settled", "order", 42, "amount", 9.5, "took", 2*time.Second, F("level", "x"))
+	m := map[string]any{}
+	if err := json.Unmarshal(b.Bytes(), &m); err != nil {
+		t.Fatal("could not unmarshal json log with fields")
+	}
+	if m["order"] != float64(42) || m["amount"] != 9.5 || m["took"] != "2s" {
+		t.Fatalf("json log did not post fields as json keys: %v", m)
+	}
+	if m["level"] != "INFO" || m["fields.level"] != "x" {
+		t.Fatalf("json log field overrode log element: %v", m)
+	}
+	b.Reset()
+	lg = New(WithWriter(b), WithFormat(LogLevel, LogMessage), WithDelim("|"))
+	lg.Warning("retry", "user", "jc", "note", "two words", "odd")
+	if r := b.String(); r != "WARNING|retry|user=jc|note=\"two words\"|!BADKEY=odd\n" {
+		t.Fatalf("std log did not post fields as key=value pairs: %q", r)
+	}
+	b.Reset()
+	var u *url.URL
+	lg = New(WithWriter(b), WithFormat(LogMessage), WithDelim("|"), WithRedactor(NewRedactor()))
+	lg.Info("nil", "url", u)
+	if r := b.String(); r != "nil|url=<nil>\n" {
+		t.Fatalf("std log did not post nil stringer as <nil>: %q", r)
+	}
+}
+
+func TestReadFields(t *testing.T) {
+	lg := New(WithDir(t.TempDir()), WithFile("fields.log"), WithConsole(false), WithFormat(LogLevel, LogSource, LogMessage))
+	lg.Info("request done", "id", "a1", "path", "/x y")
+	m, err := lg.Read()
+	if err != nil || len(m) != 1 {
+		t.Fatal("Read did not parse log record")
+	}
+	if m[0]["message"] != "request done" || m[0]["id"] != "a1" || m[0]["path"] != "/x y" {
+		t.Fatalf("Read did not parse log record fields: %v", m[0])
+	}
+}
+
+func TestWith(t *testing.T) {
+	b := new(bytes.Buffer)
+	lg := New(WithWriter(b), WithFormat(LogLevel, LogMessage), WithDelim("|"))
+	req := lg.With("request", "r1")
+	job := req.With(F("job", 7))
+	job.Info("start", "step", 1)
+	req.Info("done")
+	lg.Info("idle")
+	exp := "INFO|start|request=r1|job=7|step=1\nINFO|done|request=r1\nINFO|idle\n"
+	if r := b.String(); r != exp {
+		t.Fatalf("derived loggers did not post bound fields: %q", r)
+	}
+	lg.SetLevel(WARNING)
+	if req.Enabled(INFO) || req.Session() != lg.Session() {
+		t.Fatal("derived logger does not share configs of logger")
+	}
+}
+
+// lineWriter is a writer which is not safe for
+// concurrent use and fails if a write is not a full line
+type lineWriter struct {
+	buf   bytes.Buffer
+	lines int
+	bad   int
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	if bytes.IndexByte(p, '\n') != len(p)-1 {
+		w.bad++
+	}
+	w.lines++
+	return w.buf.Write(p)
+}
+
+func TestConcurrent(t *testing.T) {
+	const routines, records = 32, 200
+	w := &lineWriter{}
+	lg := New(WithWriter(w), WithFormat(LogJsonFmt, LogLevel, LogSession, LogMessage))
+	var wg sync.WaitGroup
+	for i := 0; i < routines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			child := lg.With("routine", i)
+			for j := 0; j < records; j++ {
+				child.Info("parallel record", "n", j)
+				if j%50 == 0 {
+					lg.SetLevel(TRACE)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if w.lines != routines*records || w.bad > 0 {
+		t.Fatalf("logger did not write each record atomically: %d lines, %d partial", w.lines, w.bad)
+	}
+	for _, ln := range bytes.Split(bytes.TrimSpace(w.buf.Bytes()), []byte("\n")) {
+		m := map[string]any{}
+		if err := json.Unmarshal(ln, &m); err != nil {
+			t.Fatalf("logger interleaved records: %q", ln)
+		}
+	}
+}
+
+func TestConcurrentActivate(t *testing.T) {
+	const routines, records = 16, 100
+	dir := t.TempDir()
+	lg := New(WithDir(dir), WithFile("c.log"), WithConsole(false), WithFormat(LogJsonFmt, LogSession, LogMessage))
+	var wg sync.WaitGroup
+	for i := 0; i < routines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < records; j++ {
+				lg.Infof("record %d", j)
+			}
+		}()
+	}
+	wg.Wait()
+	m, err := lg.Read()
+	if err != nil || len(m) != routines*records {
+		t.Fatalf("logger lost records written at activation: %d of %d", len(m), routines*records)
+	}
+	for _, r := range m {
+		if r["session"] != lg.Session() {
+			t.Fatal("concurrent activation created more than one session")
+		}
+	}
+}
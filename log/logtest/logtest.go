@@ -0,0 +1,178 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+// This package captures the records of gosimple loggers
+// in memory for unit tests, in place of a log directory
+//   rec := logtest.New(t)
+//   svc := NewService(rec.Logger())
+//   svc.Run()
+//   rec.AssertLogged(log.INFO, "service started")
+//   rec.AssertNoErrors()
+// The records of the package level log functions are
+// captured with logtest.Capture(t), which replaces the
+// default logger for the duration of the test.
+// Records are also written to the test log with t.Log,
+// which is shown if the test fails or with go test -v
+
+package logtest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jcdotter/gosimple/log"
+)
+
+// Recorder is a log.Handler which retains the
+// records of a logger for the assertions of a test
+type Recorder struct {
+	t       testing.TB
+	mu      sync.Mutex
+	logger  *log.Logger  // the logger posting to the recorder
+	records []log.Record // the records retained by the recorder
+	done    bool         // if true, the test has completed
+}
+
+// New creates a Recorder of the test 't' and a logger
+// posting records of all levels to the recorder,
+// configured by the options provided
+func New(t testing.TB, opts ...log.Option) *Recorder {
+	r := &Recorder{t: t}
+	t.Cleanup(func() {
+		r.mu.Lock()
+		r.done = true
+		r.mu.Unlock()
+	})
+	opts = append([]log.Option{log.WithLevel(log.TRACE), log.WithSession("test")}, opts...)
+	r.logger = log.New(append(opts, log.WithHandler(r))...)
+	return r
+}
+
+// Capture creates a Recorder of the test 't' and
+// replaces the default logger of the package level
+// log functions with the logger of the recorder,
+// restoring the default logger when the test completes.
+// Tests using Capture must not run in parallel
+func Capture(t testing.TB, opts ...log.Option) *Recorder {
+	r := New(t, opts...)
+	prev := log.SetDefault(r.logger)
+	t.Cleanup(func() { log.SetDefault(prev) })
+	return r
+}
+
+// Logger returns the logger posting to the recorder
+func (r *Recorder) Logger() *log.Logger {
+	return r.logger
+}
+
+// Enabled evaluates whether records of Level 'l'
+// are retained by the recorder, which is always true
+func (r *Recorder) Enabled(l log.Level) bool {
+	return true
+}
+
+// Handle retains a copy of the record 'rec'
+// and writes it to the test log
+func (r *Recorder) Handle(rec *log.Record) error {
+	c := *rec
+	c.Fields = append([]log.Field(nil), rec.Fields...)
+	c.Stack = append([]string(nil), rec.Stack...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, c)
+	if !r.done {
+		r.t.Log(format(&c))
+	}
+	return nil
+}
+
+// Records returns the records retained by the recorder
+func (r *Recorder) Records() []log.Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]log.Record(nil), r.records...)
+}
+
+// Find returns the records of Level 'l' whose message
+// or a key=value field contains the text 's'
+func (r *Recorder) Find(l log.Level, s string) []log.Record {
+	var recs []log.Record
+	for _, rec := range r.Records() {
+		if rec.Level == l && matches(&rec, s) {
+			recs = append(recs, rec)
+		}
+	}
+	return recs
+}
+
+// Reset discards the records retained by the recorder
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = nil
+}
+
+// AssertLogged fails the test if no record of Level 'l'
+// has a message or key=value field containing 's'
+func (r *Recorder) AssertLogged(l log.Level, s string) bool {
+	r.t.Helper()
+	if len(r.Find(l, s)) > 0 {
+		return true
+	}
+	r.t.Errorf("no %s record containing %q was logged", l, s)
+	return false
+}
+
+// AssertNotLogged fails the test if a record of Level 'l'
+// has a message or key=value field containing 's'
+func (r *Recorder) AssertNotLogged(l log.Level, s string) bool {
+	r.t.Helper()
+	recs := r.Find(l, s)
+	if len(recs) == 0 {
+		return true
+	}
+	r.t.Errorf("%s record containing %q was logged: %s", l, s, format(&recs[0]))
+	return false
+}
+
+// AssertNoErrors fails the test if
+// an ERROR or FATAL record was logged
+func (r *Recorder) AssertNoErrors() bool {
+	r.t.Helper()
+	ok := true
+	for _, rec := range r.Records() {
+		if rec.Level >= log.ERROR {
+			r.t.Errorf("%s record was logged: %s", rec.Level, format(&rec))
+			ok = false
+		}
+	}
+	return ok
+}
+
+// matches evaluates whether the message or
+// a key=value field of 'rec' contains 's'
+func matches(rec *log.Record, s string) bool {
+	if strings.Contains(rec.Message, s) {
+		return true
+	}
+	for _, f := range rec.Fields {
+		if strings.Contains(f.Key+"="+fmt.Sprint(f.Value), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// format formats the record 'rec' for the test log
+func format(rec *log.Record) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s %s %s", rec.Level, rec.Source, rec.Message)
+	for _, f := range rec.Fields {
+		fmt.Fprintf(b, " %s=%v", f.Key, f.Value)
+	}
+	return b.String()
+}
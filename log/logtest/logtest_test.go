@@ -0,0 +1,91 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package logtest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jcdotter/gosimple/log"
+)
+
+// fakeT is a test which retains the
+// failures reported by the assertions
+type fakeT struct {
+	testing.TB
+	errs     []string
+	logs     []string
+	cleanups []func()
+}
+
+func (t *fakeT) Helper()                   {}
+func (t *fakeT) Log(args ...any)           { t.logs = append(t.logs, fmt.Sprint(args...)) }
+func (t *fakeT) Cleanup(f func())          { t.cleanups = append(t.cleanups, f) }
+func (t *fakeT) Errorf(f string, a ...any) { t.errs = append(t.errs, fmt.Sprintf(f, a...)) }
+
+func (t *fakeT) finish() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	rec := New(t)
+	lg := rec.Logger().With("request", "r1")
+	lg.Trace("cache miss")
+	lg.Info("order placed", "order", 42)
+	rec.AssertLogged(log.TRACE, "cache miss")
+	rec.AssertLogged(log.INFO, "order=42")
+	rec.AssertNotLogged(log.INFO, "cache miss")
+	rec.AssertNoErrors()
+	r := rec.Records()
+	if len(r) != 2 || r[1].Session != "test" || !strings.Contains(r[1].Source, "logtest_test.go:") {
+		t.Fatalf("recorder did not retain records of logger: %+v", r)
+	}
+	rec.Reset()
+	if len(rec.Records()) != 0 {
+		t.Fatal("Reset did not discard records of recorder")
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	ft := &fakeT{}
+	rec := New(ft)
+	rec.Logger().Error("payment failed", log.Err(errors.New("declined")))
+	if rec.AssertLogged(log.WARNING, "payment") || rec.AssertNoErrors() || !rec.AssertLogged(log.ERROR, "declined") {
+		t.Fatal("assertions did not evaluate records of recorder")
+	}
+	if len(ft.errs) != 2 || ft.errs[0] != `no WARNING record containing "payment" was logged` ||
+		!strings.HasPrefix(ft.errs[1], "ERROR record was logged: ERROR ") {
+		t.Fatalf("assertions did not fail test: %q", ft.errs)
+	}
+	if len(ft.logs) != 1 || !strings.Contains(ft.logs[0], "payment failed error=declined") {
+		t.Fatalf("recorder did not write records to test log: %q", ft.logs)
+	}
+	ft.finish()
+	rec.Logger().Info("after test")
+	if len(ft.logs) != 1 || len(rec.Records()) != 2 {
+		t.Fatal("recorder wrote to test log after test completed")
+	}
+}
+
+func TestCapture(t *testing.T) {
+	prev := log.Default()
+	ft := &fakeT{}
+	rec := Capture(ft, log.WithLevel(log.INFO))
+	log.Trace("hidden")
+	log.Warning("disk low", "free", "1GB")
+	rec.AssertLogged(log.WARNING, "free=1GB")
+	if len(ft.errs) != 0 || len(rec.Records()) != 1 {
+		t.Fatalf("recorder did not capture records of default logger: %+v", rec.Records())
+	}
+	ft.finish()
+	if log.Default() != prev {
+		t.Fatal("Capture did not restore default logger")
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// PanicMode manages the behavior of a
+// logger after it posts a recovered panic
+type PanicMode uint
+
+const (
+	Repanic  PanicMode = iota // panic again with the recovered value
+	Exit                      // exit the application using os.Exit(1)
+	Continue                  // continue after the recovered call
+)
+
+// Recover recovers a panic of the calling goroutine
+// and posts it to the log with the full stack trace of
+// the goroutine, including the frames of the runtime
+// raising the panic, flushes the log and then panics
+// again, exits or continues in the manner of SetPanicMode.
+// It must be called directly by a deferred call, such as:
+//
+//	defer lg.Recover()
+func (lg *Logger) Recover() {
+	if v := recover(); v != nil {
+		lg.onPanic(nil, v)
+	}
+}
+
+// Go calls the function 'f' in a new goroutine,
+// posting a panic of the goroutine to the log
+// in the manner of Recover
+func (lg *Logger) Go(f func()) {
+	go func() {
+		defer lg.Recover()
+		f()
+	}()
+}
+
+// GoCtx calls the function 'f' with the context 'ctx'
+// in a new goroutine, posting a panic of the goroutine
+// to the log with the trace and span ids of the context
+// in the manner of Recover
+func (lg *Logger) GoCtx(ctx context.Context, f func(context.Context)) {
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				lg.onPanic(ctx, v)
+			}
+		}()
+		f(ctx)
+	}()
+}
+
+// SetPanicMode sets the Level of the records of recovered
+// panics, FATAL by default, and the behavior of the logger
+// after posting a recovered panic, Repanic by default
+func (lg *Logger) SetPanicMode(l Level, m PanicMode) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.panicLevel, lg.panicMode = l, m
+	}
+}
+
+// Recover recovers a panic of the calling goroutine
+// and posts it to the default log in the manner of
+// Logger.Recover. It must be called directly by a
+// deferred call, such as:
+//
+//	defer log.Recover()
+func Recover() {
+	if v := recover(); v != nil {
+		Default().onPanic(nil, v)
+	}
+}
+
+// Go calls the function 'f' in a new goroutine,
+// posting a panic of the goroutine to the default log
+func Go(f func()) {
+	Default().Go(f)
+}
+
+// GoCtx calls the function 'f' with the context 'ctx'
+// in a new goroutine, posting a panic of the goroutine
+// to the default log with the trace and span ids of the context
+func GoCtx(ctx context.Context, f func(context.Context)) {
+	Default().GoCtx(ctx, f)
+}
+
+// SetPanicMode sets the Level of the records of recovered
+// panics and the behavior of the default logger after
+// posting a recovered panic
+func SetPanicMode(l Level, m PanicMode) {
+	Default().SetPanicMode(l, m)
+}
+
+// onPanic posts the panic value 'v' recovered by the
+// caller with the stack trace of the panic and the trace
+// and span ids of the context 'ctx', flushes the log and
+// then panics again, exits or returns in the manner
+// of the panic mode of the logger
+func (lg *Logger) onPanic(ctx context.Context, v any) {
+	lg.mu.Lock()
+	l, m := lg.panicLevel, lg.panicMode
+	lg.mu.Unlock()
+	r := &Record{
+		Level:   l,
+		Time:    time.Now(),
+		Message: fmt.Sprint("panic: ", v),
+		Stack:   fullStack(2),
+	}
+	if err, ok := v.(error); ok {
+		r.Fields = lg.withFields([]any{Err(err)})
+	} else {
+		r.Fields = lg.withFields([]any{"panic", v})
+	}
+	// the source is the first frame outside the runtime
+	for _, f := range r.Stack {
+		if !isRuntimeFrame(f) {
+			r.Source = f[strings.LastIndex(f, " ")+1:]
+			break
+		}
+	}
+	r.TraceID, r.SpanID = TraceFromContext(ctx)
+	if lg.Enabled(l) {
+		lg.post(r)
+	}
+	lg.flushWithin(exitFlushTimeout)
+	switch m {
+	case Repanic:
+		panic(v)
+	case Exit:
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func crash(v any) {
+	panic(v)
+}
+
+func TestRecover(t *testing.T) {
+	h := &recordHandler{}
+	lg := New(WithSession("s1"), WithHandler(h))
+	func() {
+		defer func() {
+			if v := recover(); v != "boom" {
+				t.Fatalf("Recover did not panic again with the recovered value: %v", v)
+			}
+		}()
+		defer lg.Recover()
+		crash("boom")
+	}()
+	if len(h.records) != 1 {
+		t.Fatal("Recover did not post the panic to log")
+	}
+	r := h.records[0]
+	if r.Level != FATAL || r.Message != "panic: boom" || r.Session != "s1" || r.Fields[0] != F("panic", "boom") {
+		t.Fatalf("Recover did not post the panic record: %+v", r)
+	}
+	if len(r.Stack) < 2 || !strings.HasPrefix(r.Stack[0], "runtime.gopanic ") ||
+		!strings.HasSuffix(strings.Fields(r.Stack[1])[0], ".crash") || !strings.Contains(r.Source, "recover_test.go:") {
+		t.Fatalf("Recover did not post the stack trace of the panic: %s %v", r.Source, r.Stack)
+	}
+	h.records = nil
+	func() {
+		defer func() { recover() }()
+		defer lg.Recover()
+		deep(100)
+	}()
+	if s := h.records[0].Stack; len(s) < 100 || !strings.HasPrefix(s[len(s)-1], "runtime.goexit ") {
+		t.Fatalf("Recover did not post the full stack trace of the goroutine: %d frames", len(s))
+	}
+}
+
+// deep panics at the depth 'n' of recursive calls
+func deep(n int) {
+	if n == 0 {
+		crash("deep")
+	}
+	deep(n - 1)
+}
+
+// chanHandler is a handler which
+// sends the records it handles
+type chanHandler chan Record
+
+func (h chanHandler) Enabled(l Level) bool {
+	return true
+}
+
+func (h chanHandler) Handle(r *Record) error {
+	h <- *r
+	return nil
+}
+
+func TestGo(t *testing.T) {
+	h := make(chanHandler, 2)
+	lg := New(WithHandler(h), WithPanicMode(ERROR, Continue))
+	lg.Go(func() { crash(errors.New("bad state")) })
+	lg.GoCtx(ContextWithTrace(context.Background(), "t1", "s1"), func(ctx context.Context) { crash(1) })
+	rs := []Record{<-h, <-h}
+	if rs[0].Message == "panic: 1" {
+		rs[0], rs[1] = rs[1], rs[0]
+	}
+	if r := rs[0]; r.Level != ERROR || r.Message != "panic: bad state" || r.Fields[0].Key != "error" {
+		t.Fatalf("Go did not post the error of the panic: %+v", r)
+	}
+	if r := rs[1]; r.TraceID != "t1" || r.SpanID != "s1" || r.Fields[0] != F("panic", 1) {
+		t.Fatalf("GoCtx did not post the panic with the trace of the context: %+v", r)
+	}
+}
@@ -0,0 +1,368 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// names of the built-in redaction rules
+const (
+	RedactEmail  = "email"  // email addresses
+	RedactPAN    = "pan"    // payment card numbers passing the Luhn check
+	RedactBearer = "bearer" // bearer tokens of authorization headers
+	RedactSSN    = "ssn"    // US social security numbers
+	RedactField  = "field"  // values of fields in the deny-list
+)
+
+// defaultMask replaces the values redacted from records
+const defaultMask = "[REDACTED]"
+
+// defaultDenyList are the field names
+// whose values are always redacted
+var defaultDenyList = []string{"password", "passwd", "secret", "token", "apikey", "api_key", "authorization"}
+
+// redactRule is a rule of a Redactor which masks
+// the matches of its pattern that are valid
+type redactRule struct {
+	name  string            // the name of the rule
+	re    *regexp.Regexp    // the pattern of the values masked
+	valid func(string) bool // the validation of the matches, if any
+}
+
+// builtinRules are the built-in rules of a Redactor
+var builtinRules = []redactRule{
+	{RedactEmail, regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), nil},
+	{RedactBearer, regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`), nil},
+	{RedactPAN, regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`), luhn},
+	{RedactSSN, regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`), validSSN},
+}
+
+// Redaction reports a value redacted
+// from a record by a Redactor in test mode
+type Redaction struct {
+	Rule string // the name of the rule which matched the value
+	Key  string // the key of the field of the value, or 'message'
+	Text string // the value redacted
+}
+
+// Redactor masks personal data and secrets in
+// the messages and field values of records using
+// built-in detectors, user-defined regex rules
+// and a deny-list of field names
+type Redactor struct {
+	rules []redactRule    // the rules of the values masked
+	deny  map[string]bool // the field names whose values are masked
+	mask  string          // the text replacing masked values
+	test  bool            // if true, redactions are reported
+	mu    sync.Mutex      // guards the report
+	rep   []Redaction     // the redactions reported in test mode
+}
+
+// RedactOption configures a Redactor
+// created with log.NewRedactor
+type RedactOption func(*Redactor)
+
+// NewRedactor creates a Redactor masking emails, payment
+// card numbers, bearer tokens, SSNs and the values of
+// fields named password, secret, token or the like,
+// extended by the options provided
+func NewRedactor(opts ...RedactOption) *Redactor {
+	rd := &Redactor{
+		rules: append([]redactRule{}, builtinRules...),
+		deny:  map[string]bool{},
+		mask:  defaultMask,
+	}
+	for _, n := range defaultDenyList {
+		rd.deny[n] = true
+	}
+	for _, o := range opts {
+		o(rd)
+	}
+	return rd
+}
+
+// RedactRule adds a rule named 'name' which masks
+// the matches of the pattern 're' in records
+func RedactRule(name string, re *regexp.Regexp) RedactOption {
+	return func(rd *Redactor) { rd.rules = append(rd.rules, redactRule{name: name, re: re}) }
+}
+
+// RedactFields adds field names to the deny-list of the
+// redactor. The values of fields whose key, or the last
+// segment of a dotted key, matches a name regardless
+// of case are masked
+func RedactFields(names ...string) RedactOption {
+	return func(rd *Redactor) {
+		for _, n := range names {
+			rd.deny[strings.ToLower(n)] = true
+		}
+	}
+}
+
+// RedactWithout removes the built-in rules
+// named in 'names' from the redactor
+func RedactWithout(names ...string) RedactOption {
+	return func(rd *Redactor) {
+		rs := rd.rules[:0]
+		for _, r := range rd.rules {
+			if !contains(names, r.name) {
+				rs = append(rs, r)
+			}
+		}
+		rd.rules = rs
+	}
+}
+
+// RedactMask sets the text replacing
+// the values masked by the redactor
+func RedactMask(m string) RedactOption {
+	return func(rd *Redactor) { rd.mask = m }
+}
+
+// RedactTestMode reports the values masked by
+// the redactor, which are then returned by Redactions.
+// Test mode retains the values redacted and must
+// not be used in production
+func RedactTestMode() RedactOption {
+	return func(rd *Redactor) { rd.test = true }
+}
+
+// Redactions returns the values masked by the
+// redactor in test mode since the last call
+func (rd *Redactor) Redactions() []Redaction {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	rep := rd.rep
+	rd.rep = nil
+	return rep
+}
+
+// Redact masks the message and field values of the
+// record 'r' in place. The fields of the record are
+// copied before they are masked. Structs, maps and
+// slices are walked, and are replaced by maps and
+// slices of their masked values if any is masked
+// so the values of the caller are not changed.
+// Integers are masked by the deny-list and as payment
+// card numbers of their decimal text, while other rules
+// do not apply to numbers
+func (rd *Redactor) Redact(r *Record) {
+	r.Message = rd.redactText("message", r.Message)
+	copied := false
+	for i, f := range r.Fields {
+		v, ok := rd.redactValue(f.Key, f.Value, 0)
+		if !ok {
+			continue
+		}
+		if !copied {
+			r.Fields = append([]Field{}, r.Fields...)
+			copied = true
+		}
+		r.Fields[i].Value = v
+	}
+}
+
+// maxRedactDepth is the max depth of the composite
+// values walked by a Redactor, which bounds cycles
+const maxRedactDepth = 8
+
+// redactValue returns the masked value 'v' of the
+// field 'key' and whether any part of the value was
+// masked. The fields of structs and the values of maps
+// are masked as fields named 'key.field'
+func (rd *Redactor) redactValue(key string, v any, depth int) (any, bool) {
+	if v == nil {
+		return nil, false
+	}
+	k := strings.ToLower(key)
+	if rd.deny[k] || rd.deny[k[strings.LastIndex(k, ".")+1:]] {
+		rd.report(RedactField, key, fmt.Sprint(v))
+		return rd.mask, true
+	}
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case error:
+		s = errorText(v)
+	case fmt.Stringer:
+		s = stringerText(v)
+	case []byte:
+		s = string(v)
+	default:
+		rv := reflect.ValueOf(v)
+		if isInteger(rv.Kind()) {
+			return rd.redactNumber(key, fmt.Sprint(v))
+		}
+		if depth >= maxRedactDepth {
+			return nil, false
+		}
+		return rd.redactComposite(key, rv, depth+1)
+	}
+	if m := rd.redactText(key, s); m != s {
+		return m, true
+	}
+	return nil, false
+}
+
+// redactComposite returns the struct, map, slice or
+// array 'v' of the field 'key' as a map or slice of
+// its masked values, if any part of the value was masked
+func (rd *Redactor) redactComposite(key string, v reflect.Value, depth int) (any, bool) {
+	masked := false
+	value := func(k string, e reflect.Value) any {
+		if m, ok := rd.redactValue(k, e.Interface(), depth); ok {
+			masked = true
+			return m
+		}
+		return e.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil, false
+		}
+		return rd.redactValue(key, v.Elem().Interface(), depth)
+	case reflect.Struct:
+		m := map[string]any{}
+		for i := 0; i < v.NumField(); i++ {
+			sf := v.Type().Field(i)
+			n := strings.Split(sf.Tag.Get("json"), ",")[0]
+			if !sf.IsExported() || n == "-" {
+				continue
+			}
+			if n == "" {
+				n = sf.Name
+			}
+			m[n] = value(key+"."+n, v.Field(i))
+		}
+		return m, masked
+	case reflect.Map:
+		m := map[string]any{}
+		for it := v.MapRange(); it.Next(); {
+			n := fmt.Sprint(it.Key().Interface())
+			m[n] = value(key+"."+n, it.Value())
+		}
+		return m, masked
+	case reflect.Slice, reflect.Array:
+		s := make([]any, v.Len())
+		for i := range s {
+			s[i] = value(key, v.Index(i))
+		}
+		return s, masked
+	}
+	return nil, false
+}
+
+// redactNumber masks the decimal text 's' of an
+// integer of 'key' if it is a payment card number
+func (rd *Redactor) redactNumber(key, s string) (any, bool) {
+	for _, r := range rd.rules {
+		if r.name == RedactPAN && r.re.MatchString(s) && (r.valid == nil || r.valid(s)) {
+			rd.report(r.name, key, s)
+			return rd.mask, true
+		}
+	}
+	return nil, false
+}
+
+// isInteger evaluates whether 'k'
+// is the kind of an integer
+func isInteger(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Uint64
+}
+
+// redactText masks the matches of the rules
+// of the redactor in the text 's' of 'key'
+func (rd *Redactor) redactText(key, s string) string {
+	for _, r := range rd.rules {
+		s = r.re.ReplaceAllStringFunc(s, func(m string) string {
+			if r.valid != nil && !r.valid(m) {
+				return m
+			}
+			rd.report(r.name, key, m)
+			return rd.mask
+		})
+	}
+	return s
+}
+
+// report records the redaction of the text 's'
+// of 'key' by the rule 'rule' in test mode
+func (rd *Redactor) report(rule, key, s string) {
+	if !rd.test {
+		return
+	}
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	rd.rep = append(rd.rep, Redaction{Rule: rule, Key: key, Text: s})
+}
+
+// luhn evaluates whether the digits of the card
+// number 's' pass the Luhn checksum
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && n <= 19 && sum%10 == 0
+}
+
+// validSSN evaluates whether 's' is a valid
+// SSN of the format 123-45-6789
+func validSSN(s string) bool {
+	area, group, serial := s[:3], s[4:6], s[7:]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// contains evaluates whether 'ss' contains 's'
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// WithRedactor masks the records posted by a new
+// Logger using the redactor 'rd'
+func WithRedactor(rd *Redactor) Option {
+	return func(lg *Logger) { lg.SetRedactor(rd) }
+}
+
+// SetRedactor masks the messages and field values
+// of the records of the logger using the redactor
+// 'rd' before they are posted to the handler
+func (lg *Logger) SetRedactor(rd *Redactor) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.redactor = rd
+	}
+}
+
+// SetRedactor masks the messages and field values
+// of the records of the default logger
+func SetRedactor(rd *Redactor) {
+	Default().SetRedactor(rd)
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	rd := NewRedactor(RedactRule("order", regexp.MustCompile(`ord-\d+`)), RedactFields("PIN"), RedactTestMode())
+	fields := []Field{
+		F("card", "4111 1111 1111 1111"),
+		F("number", 4111111111111112),
+		F("auth", "Bearer abc.def-123"),
+		F("user.Password", "hunter2"),
+		F("pin", 1234),
+		F("err", errors.New("no account for 078-05-1120")),
+		F("count", 42),
+		F("id", uint64(4111111111111111)),
+		F("user", struct {
+			Name  string `json:"name"`
+			Email string `json:"email"`
+			Token string
+		}{"jc", "jc@example.com", "t1"}),
+		F("cards", []string{"4111 1111 1111 1111", "none"}),
+		F("headers", map[string]any{"Authorization": "Bearer abc", "Accept": "*/*"}),
+		F("tags", []string{"a", "b"}),
+	}
+	r := &Record{Message: "mail jc@example.com about ord-77", Fields: fields}
+	rd.Redact(r)
+	if r.Message != "mail [REDACTED] about [REDACTED]" {
+		t.Fatalf("redactor did not mask message: %q", r.Message)
+	}
+	exp := []any{"[REDACTED]", 4111111111111112, "[REDACTED]", "[REDACTED]", "[REDACTED]", "no account for [REDACTED]", 42,
+		"[REDACTED]", map[string]any{"name": "jc", "email": "[REDACTED]", "Token": "[REDACTED]"},
+		[]any{"[REDACTED]", "none"}, map[string]any{"Authorization": "[REDACTED]", "Accept": "*/*"}, []string{"a", "b"}}
+	for i, f := range r.Fields {
+		if !reflect.DeepEqual(f.Value, exp[i]) {
+			t.Fatalf("redactor did not mask field %s: %v", f.Key, f.Value)
+		}
+	}
+	if fields[0].Value != "4111 1111 1111 1111" {
+		t.Fatal("redactor masked the fields of the caller")
+	}
+	rep := rd.Redactions()
+	rules := []string{}
+	for _, x := range rep {
+		rules = append(rules, x.Rule+":"+x.Key)
+	}
+	if !reflect.DeepEqual(rules, []string{"email:message", "order:message", "pan:card", "bearer:auth", "field:user.Password",
+		"field:pin", "ssn:err", "pan:id", "email:user.email", "field:user.Token", "pan:cards", "field:headers.Authorization"}) {
+		t.Fatalf("redactor did not report redactions in test mode: %v", rep)
+	}
+	if rep[2].Text != "4111 1111 1111 1111" || rd.Redactions() != nil {
+		t.Fatalf("redactor did not report redacted values: %v", rep)
+	}
+	rd = NewRedactor(RedactWithout(RedactEmail), RedactMask("***"))
+	r = &Record{Message: "jc@example.com 123-45-6789 000-12-3456"}
+	rd.Redact(r)
+	if r.Message != "jc@example.com *** 000-12-3456" || rd.Redactions() != nil {
+		t.Fatalf("redactor did not apply its options: %q", r.Message)
+	}
+}
+
+func TestLoggerRedactor(t *testing.T) {
+	b := new(bytes.Buffer)
+	lg := New(WithWriter(b), WithFormat(LogJsonFmt, LogMessage), WithRedactor(NewRedactor())).With("secret", "s3")
+	lg.Info("login jc@example.com", "token", "t1")
+	lg.Info("again")
+	if s := b.String(); s != `{"message":"login [REDACTED]","secret":"[REDACTED]","token":"[REDACTED]"}`+"\n"+
+		`{"message":"again","secret":"[REDACTED]"}`+"\n" {
+		t.Fatalf("logger did not redact records before formatting: %s", s)
+	}
+	if lg.fields[0].Value != "s3" {
+		t.Fatal("logger redacted its context fields")
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rotation intervals: cutover of log files
+const (
+	Hourly = time.Hour      // rotate log file at the start of each hour
+	Daily  = 24 * time.Hour // rotate log file at the start of each day
+)
+
+// rotateTimeFmt is the format of the timestamp
+// added to the name of rotated log files
+const rotateTimeFmt = `20060102-150405.000`
+
+// Rotation configures the rotation of log files
+// by size and time and the retention of rotated files
+type Rotation struct {
+	MaxSize    int64         // the max size in bytes of a log file, 0 for no max
+	Interval   time.Duration // the cutover interval of log files, such as Daily, 0 for none
+	MaxBackups int           // the max count of rotated files retained, 0 for no max
+	MaxAge     time.Duration // the max age of rotated files retained, 0 for no max
+	Compress   bool          // if true, rotated files are compressed with gzip
+	OnError    func(error)   // called with the errors compressing and removing rotated files, if set
+}
+
+// RotateWriter is an io.Writer to a log file which
+// rotates the file by size and time. Rotated files
+// are renamed with the start of the period they cover,
+// such as 'session-20060102-150405.000.log', which is
+// the start of the interval of the file or the time the
+// file was opened. Rotated files are compressed and
+// removed per the retention of the rotation in the
+// background, reporting errors to Rotation.OnError
+type RotateWriter struct {
+	mu      sync.Mutex
+	path    string           // the path of the active log file
+	rot     Rotation         // the rotation configs
+	file    *os.File         // the active log file
+	size    int64            // the size of the active log file
+	start   time.Time        // the start of the period of the active log file
+	cutover time.Time        // the time of the next rotation by interval
+	now     func() time.Time // the clock used for rotation
+	bg      chan struct{}    // closed when the last background compression and pruning ends
+}
+
+// NewRotateWriter opens the log file at 'path'
+// in append mode and returns a writer which rotates
+// the file using the Rotation 'r' provided
+func NewRotateWriter(path string, r Rotation) (*RotateWriter, error) {
+	w := &RotateWriter{path: path, rot: r, now: time.Now}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write writes 'p' to the active log file,
+// rotating the file prior to the write if the
+// write exceeds the max size or the cutover has passed
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	over := w.rot.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.rot.MaxSize
+	if over || (!w.cutover.IsZero() && !w.now().Before(w.cutover)) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the active log file
+// regardless of its size and cutover
+func (w *RotateWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotate()
+}
+
+// Close closes the active log file after
+// the rotated files are compressed and pruned
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.bg != nil {
+		<-w.bg
+	}
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// open opens the active log file and sets its
+// size, the start of its period and next cutover.
+// The period of a file opened at a cutover starts
+// at the start of the interval, and otherwise
+// at the time the file is opened
+func (w *RotateWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	now := w.now()
+	w.file, w.size, w.start = f, info.Size(), now
+	if w.rot.Interval > 0 {
+		if w.cutover.IsZero() || !now.Before(w.cutover) {
+			w.start = intervalStart(now, w.rot.Interval)
+		}
+		w.cutover = nextCutover(now, w.rot.Interval)
+	}
+	return nil
+}
+
+// rotate renames the active log file with the
+// start of its period, opens a new active file,
+// and compresses the rotated file and removes
+// rotated files beyond retention in the background
+func (w *RotateWriter) rotate() error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	name := w.backupName(w.start)
+	if err := os.Rename(w.path, name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	if !w.rot.Compress && w.rot.MaxBackups <= 0 && w.rot.MaxAge <= 0 {
+		return nil
+	}
+	now, prev, done := w.now(), w.bg, make(chan struct{})
+	w.bg = done
+	go func() {
+		defer close(done)
+		if prev != nil { // compress and prune files in order of rotation
+			<-prev
+		}
+		if w.rot.Compress {
+			if err := compressFile(name); err != nil {
+				w.report(err)
+			}
+		}
+		if err := w.prune(now); err != nil {
+			w.report(err)
+		}
+	}()
+	return nil
+}
+
+// report reports the error 'err' of the background
+// compression and pruning of the rotated files
+func (w *RotateWriter) report(err error) {
+	if w.rot.OnError != nil {
+		w.rot.OnError(err)
+	}
+}
+
+// backupName returns an unused name of a
+// rotated file whose period starts at time 't'
+func (w *RotateWriter) backupName(t time.Time) string {
+	ext := filepath.Ext(w.path)
+	base := strings.TrimSuffix(w.path, ext) + "-" + t.Format(rotateTimeFmt)
+	name := base + ext
+	for i := 1; fileExists(name) || fileExists(name+".gz"); i++ {
+		name = base + "." + strconv.Itoa(i) + ext
+	}
+	return name
+}
+
+// backups returns the rotated files of the
+// active log file, sorted newest first
+func (w *RotateWriter) backups() ([]os.FileInfo, error) {
+	dir := filepath.Dir(w.path)
+	ext := filepath.Ext(w.path)
+	prefix := strings.TrimSuffix(filepath.Base(w.path), ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []os.FileInfo
+	for _, e := range entries {
+		n := e.Name()
+		if e.IsDir() || !strings.HasPrefix(n, prefix) {
+			continue
+		}
+		if !strings.HasSuffix(n, ext) && !strings.HasSuffix(n, ext+".gz") {
+			continue
+		}
+		if len(n) < len(prefix)+len(rotateTimeFmt) {
+			continue
+		}
+		if _, err := time.Parse(rotateTimeFmt, n[len(prefix):len(prefix)+len(rotateTimeFmt)]); err != nil {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			files = append(files, info)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() > files[j].Name()
+	})
+	return files, nil
+}
+
+// prune removes the rotated files beyond the
+// max backups or older than the max age at time 'now'
+func (w *RotateWriter) prune(now time.Time) error {
+	if w.rot.MaxBackups <= 0 && w.rot.MaxAge <= 0 {
+		return nil
+	}
+	files, err := w.backups()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(w.path)
+	for i, f := range files {
+		old := w.rot.MaxAge > 0 && now.Sub(f.ModTime()) > w.rot.MaxAge
+		if (w.rot.MaxBackups > 0 && i >= w.rot.MaxBackups) || old {
+			if err := os.Remove(filepath.Join(dir, f.Name())); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// intervalStart returns the start of the interval
+// 'd' including time 't'. Intervals are aligned
+// to midnight of the location of 't', so hourly
+// intervals start on the hour of the local time
+// in zones with offsets of half hours
+func intervalStart(t time.Time, d time.Duration) time.Time {
+	y, m, dd := t.Date()
+	if d >= Daily {
+		return time.Date(y, m, dd, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, dd, 0, 0, 0, int(clockTime(t).Truncate(d)), t.Location())
+}
+
+// nextCutover returns the start of the interval
+// following time 't'. Intervals of a day or
+// longer start at midnight of the local time and
+// shorter intervals do not extend past midnight
+func nextCutover(t time.Time, d time.Duration) time.Time {
+	y, m, dd := t.Date()
+	if d >= Daily {
+		days := int(d / Daily)
+		return time.Date(y, m, dd+days, 0, 0, 0, 0, t.Location())
+	}
+	ns := clockTime(t).Truncate(d) + d
+	if ns > Daily {
+		ns = Daily
+	}
+	return time.Date(y, m, dd, 0, 0, 0, int(ns), t.Location())
+}
+
+// clockTime returns the wall clock
+// time of the day of time 't'
+func clockTime(t time.Time) time.Duration {
+	h, m, s := t.Clock()
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(s)*time.Second + time.Duration(t.Nanosecond())
+}
+
+// compressFile compresses the file at 'name'
+// to 'name.gz' and removes the file
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+	src.Close()
+	return os.Remove(name)
+}
+
+// fileExists evaluates whether a file exists at 'name'
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
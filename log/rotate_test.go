@@ -0,0 +1,109 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotateSize(t *testing.T) {
+	dir := t.TempDir()
+	var errs []error
+	clock := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	w := &RotateWriter{path: filepath.Join(dir, "s.log"), now: func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}, rot: Rotation{MaxSize: 10, MaxBackups: 2, Compress: true, OnError: func(err error) { errs = append(errs, err) }}}
+	if err := w.open(); err != nil {
+		t.Fatal("could not open rotate writer:", err)
+	}
+	for _, r := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := w.Write([]byte(r)); err != nil {
+			t.Fatal("could not write to rotate writer:", err)
+		}
+	}
+	w.Close()
+	b, _ := os.ReadFile(filepath.Join(dir, "s.log"))
+	if string(b) != "fourth\n" {
+		t.Fatalf("rotate writer did not rotate file at max size: %q", b)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "s-*.log*"))
+	if len(files) != 2 || len(errs) != 0 || !strings.HasSuffix(files[1], ".gz") {
+		t.Fatalf("rotate writer did not retain max backups: %v", files)
+	}
+	f, _ := os.Open(files[1])
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal("rotate writer did not compress rotated file:", err)
+	}
+	if b, _ := io.ReadAll(gz); string(b) != "third\n" {
+		t.Fatalf("rotated file does not contain rotated records: %q", b)
+	}
+}
+
+func TestRotateInterval(t *testing.T) {
+	dir := t.TempDir()
+	clock := time.Date(2022, 1, 1, 23, 59, 0, 0, time.Local)
+	w := &RotateWriter{path: filepath.Join(dir, "s.log"), rot: Rotation{Interval: Daily}, now: func() time.Time { return clock }}
+	if err := w.open(); err != nil {
+		t.Fatal("could not open rotate writer:", err)
+	}
+	defer w.Close()
+	w.Write([]byte("day one\n"))
+	clock = clock.Add(2 * time.Minute)
+	w.Write([]byte("day two\n"))
+	files, _ := filepath.Glob(filepath.Join(dir, "s-20220101-000000.000.log"))
+	if len(files) != 1 {
+		t.Fatalf("rotate writer did not rotate file at daily cutover: %v", files)
+	}
+	if b, _ := os.ReadFile(files[0]); string(b) != "day one\n" {
+		t.Fatalf("rotated file does not contain records prior to cutover: %q", b)
+	}
+	if !w.cutover.Equal(time.Date(2022, 1, 3, 0, 0, 0, 0, time.Local)) {
+		t.Fatal("rotate writer did not set next cutover:", w.cutover)
+	}
+}
+
+func TestRotateCutover(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+1800)
+	for _, tc := range []struct {
+		t          time.Time
+		d          time.Duration
+		start, cut time.Time
+	}{
+		{time.Date(2022, 1, 1, 10, 40, 0, 0, ist), Hourly,
+			time.Date(2022, 1, 1, 10, 0, 0, 0, ist), time.Date(2022, 1, 1, 11, 0, 0, 0, ist)},
+		{time.Date(2022, 1, 1, 23, 10, 0, 0, ist), 15 * time.Minute,
+			time.Date(2022, 1, 1, 23, 0, 0, 0, ist), time.Date(2022, 1, 1, 23, 15, 0, 0, ist)},
+		{time.Date(2022, 1, 1, 23, 10, 0, 0, ist), 7 * time.Hour,
+			time.Date(2022, 1, 1, 21, 0, 0, 0, ist), time.Date(2022, 1, 2, 0, 0, 0, 0, ist)},
+		{time.Date(2022, 1, 1, 23, 10, 0, 0, ist), Daily,
+			time.Date(2022, 1, 1, 0, 0, 0, 0, ist), time.Date(2022, 1, 2, 0, 0, 0, 0, ist)},
+	} {
+		if s, c := intervalStart(tc.t, tc.d), nextCutover(tc.t, tc.d); !s.Equal(tc.start) || !c.Equal(tc.cut) {
+			t.Fatalf("interval %v of %v starts %v and ends %v, expected %v and %v", tc.d, tc.t, s, c, tc.start, tc.cut)
+		}
+	}
+}
+
+func TestLoggerRotation(t *testing.T) {
+	dir := t.TempDir()
+	lg := New(WithDir(dir), WithFile("r.log"), WithConsole(false), WithRotation(Rotation{MaxSize: 64}))
+	for i := 0; i < 4; i++ {
+		lg.Info(strings.Repeat("x", 40))
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "r-*.log"))
+	if len(files) != 3 {
+		t.Fatalf("logger did not rotate log file: %v", files)
+	}
+}
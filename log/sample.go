@@ -0,0 +1,259 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// suppressedKey is the key of the count of
+// records suppressed in a summary record
+const suppressedKey = "suppressed"
+
+// Sampling limits the records posted by each call
+// site of a logger, identified by its source file and
+// line, to the First records of each interval and then
+// every Thereafter record of the interval
+type Sampling struct {
+	First      int           // the records posted per call site per interval
+	Thereafter int           // post every Mth record after First, 0 to post none
+	Interval   time.Duration // the sampling interval, 1s by default
+}
+
+// RateLimit limits the records posted at a level
+// using a token bucket refilled at Rate records per
+// second holding at most Burst records
+type RateLimit struct {
+	Rate  float64 // the records posted per second
+	Burst int     // the records posted at once, 1 by default
+}
+
+// limiter samples and rate limits the records of a
+// logger, counting the records it suppresses
+type limiter struct {
+	mu      sync.Mutex
+	sample  Sampling               // the sampling of the call sites, if any
+	sites   map[string]*siteCount  // the counts of the call sites by source
+	buckets map[Level]*tokenBucket // the rate limits by level
+	now     func() time.Time       // the clock of the limiter
+}
+
+// siteCount counts the records of a
+// call site in its sampling interval
+type siteCount struct {
+	level      Level     // the level of the last record of the site
+	start      time.Time // the start of the interval
+	n          int       // the records of the interval
+	suppressed int       // the records suppressed in the interval
+}
+
+// tokenBucket is the rate limit of a level
+type tokenBucket struct {
+	RateLimit
+	tokens     float64   // the records which may be posted
+	last       time.Time // the time the tokens were refilled
+	suppressed int       // the records suppressed since the last posted
+}
+
+// newLimiter creates a limiter which
+// neither samples nor rate limits records
+func newLimiter() *limiter {
+	return &limiter{
+		sites:   map[string]*siteCount{},
+		buckets: map[Level]*tokenBucket{},
+		now:     time.Now,
+	}
+}
+
+// allow evaluates whether the record of Level 'l' from
+// the call site 'src' is posted and returns the summary
+// records of the records suppressed before it, if any.
+// FATAL records are always posted
+func (lm *limiter) allow(l Level, src string) (bool, []*Record) {
+	if l == FATAL {
+		return true, nil
+	}
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	now := lm.now()
+	var sums []*Record
+	if lm.sample.First > 0 || lm.sample.Thereafter > 0 {
+		s := lm.sites[src]
+		if s == nil {
+			s = &siteCount{start: now}
+			lm.sites[src] = s
+		} else if now.Sub(s.start) >= lm.sample.interval() {
+			if r := s.summary(src, now); r != nil {
+				sums = append(sums, r)
+			}
+			s.start, s.n = now, 0
+		}
+		s.level = l
+		s.n++
+		if m := s.n - lm.sample.First; m > 0 && (lm.sample.Thereafter == 0 || m%lm.sample.Thereafter != 0) {
+			s.suppressed++
+			return false, sums
+		}
+	}
+	if b := lm.buckets[l]; b != nil {
+		b.refill(now)
+		if b.tokens < 1 {
+			b.suppressed++
+			return false, sums
+		}
+		b.tokens--
+		if r := b.summary(l, now); r != nil {
+			sums = append(sums, r)
+		}
+	}
+	return true, sums
+}
+
+// pending returns the summary records of the records
+// suppressed by the limiter which have not been summarized
+func (lm *limiter) pending() []*Record {
+	lm.mu.Lock()
+	defer lm.mu.Unlock()
+	now := lm.now()
+	var sums []*Record
+	srcs := make([]string, 0, len(lm.sites))
+	for src := range lm.sites {
+		srcs = append(srcs, src)
+	}
+	sort.Strings(srcs)
+	for _, src := range srcs {
+		if r := lm.sites[src].summary(src, now); r != nil {
+			sums = append(sums, r)
+		}
+	}
+	for l := TRACE; l < FATAL; l++ {
+		if b := lm.buckets[l]; b != nil {
+			if r := b.summary(l, now); r != nil {
+				sums = append(sums, r)
+			}
+		}
+	}
+	return sums
+}
+
+// interval returns the sampling interval
+func (s *Sampling) interval() time.Duration {
+	if s.Interval <= 0 {
+		return time.Second
+	}
+	return s.Interval
+}
+
+// summary returns the summary record of the records
+// suppressed at the call site 'src' and resets the count,
+// or nil if no records were suppressed
+func (s *siteCount) summary(src string, now time.Time) *Record {
+	if s.suppressed == 0 {
+		return nil
+	}
+	r := &Record{
+		Level:   s.level,
+		Time:    now,
+		Source:  src,
+		Message: fmt.Sprintf("suppressed %d similar messages", s.suppressed),
+		Fields:  []Field{{suppressedKey, s.suppressed}},
+	}
+	s.suppressed = 0
+	return r
+}
+
+// refill adds the tokens accrued since
+// the last refill to the bucket
+func (b *tokenBucket) refill(now time.Time) {
+	burst := float64(b.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else if d := now.Sub(b.last); d > 0 {
+		b.tokens += d.Seconds() * b.Rate
+	}
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+}
+
+// summary returns the summary record of the records
+// of Level 'l' suppressed by the bucket and resets the
+// count, or nil if no records were suppressed
+func (b *tokenBucket) summary(l Level, now time.Time) *Record {
+	if b.suppressed == 0 {
+		return nil
+	}
+	r := &Record{
+		Level:   l,
+		Time:    now,
+		Message: fmt.Sprintf("suppressed %d %s messages", b.suppressed, l),
+		Fields:  []Field{{suppressedKey, b.suppressed}},
+	}
+	b.suppressed = 0
+	return r
+}
+
+// WithSampling samples the records posted by
+// each call site of a new Logger
+func WithSampling(s Sampling) Option {
+	return func(lg *Logger) { lg.SetSampling(s) }
+}
+
+// WithRateLimit limits the records of Level 'l'
+// posted by a new Logger
+func WithRateLimit(l Level, r RateLimit) Option {
+	return func(lg *Logger) { lg.SetRateLimit(l, r) }
+}
+
+// SetSampling samples the records posted by each
+// call site of the logger. Records suppressed at a call
+// site are summarized by a record posted with the next
+// record of the call site after the interval, such as
+// 'suppressed 1234 similar messages', or by Flush
+func (lg *Logger) SetSampling(s Sampling) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		if lg.limiter == nil {
+			lg.limiter = newLimiter()
+		}
+		lg.limiter.sample = s
+	}
+}
+
+// SetRateLimit limits the records of Level 'l' posted
+// by the logger. Records suppressed at the rate limit
+// are summarized by a record posted with the next record
+// of the level within the limit, or by Flush
+func (lg *Logger) SetRateLimit(l Level, r RateLimit) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() && l < FATAL {
+		if lg.limiter == nil {
+			lg.limiter = newLimiter()
+		}
+		lg.limiter.buckets[l] = &tokenBucket{RateLimit: r}
+	}
+}
+
+// SetSampling samples the records posted by
+// each call site of the default logger
+func SetSampling(s Sampling) {
+	Default().SetSampling(s)
+}
+
+// SetRateLimit limits the records of Level 'l'
+// posted by the default logger
+func SetRateLimit(l Level, r RateLimit) {
+	Default().SetRateLimit(l, r)
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSampling(t *testing.T) {
+	h := &recordHandler{}
+	lg := New(WithHandler(h), WithSampling(Sampling{First: 2, Thereafter: 3, Interval: time.Minute}))
+	clock := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	lg.limiter.now = func() time.Time { return clock }
+	retry := func(i int) { lg.Warning("retry", "i", i) }
+	for i := 0; i < 10; i++ {
+		retry(i)
+	}
+	lg.Info("other site")
+	var got []any
+	for _, r := range h.records[:len(h.records)-1] {
+		got = append(got, r.Fields[0].Value)
+	}
+	// records 0 and 1 are posted, then every 3rd of the rest
+	if len(got) != 4 || got[0] != 0 || got[1] != 1 || got[2] != 4 || got[3] != 7 || h.records[4].Message != "other site" {
+		t.Fatalf("sampling did not post first and every Mth record: %v", got)
+	}
+	clock = clock.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		retry(i)
+	}
+	rs := h.records[5:]
+	if len(rs) != 3 || rs[0].Message != "suppressed 6 similar messages" || rs[0].Level != WARNING ||
+		rs[0].Fields[0] != F("suppressed", 6) || rs[0].Source != h.records[0].Source || rs[1].Fields[0] != F("i", 0) {
+		t.Fatalf("sampling did not summarize suppressed records in next interval: %+v", rs)
+	}
+	retry(2)
+	lg.Flush()
+	if rs := h.records[8:]; len(rs) != 1 || rs[0].Message != "suppressed 1 similar messages" {
+		t.Fatalf("Flush did not summarize suppressed records: %+v", rs)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	h := &recordHandler{}
+	lg := New(WithHandler(h), WithRateLimit(ERROR, RateLimit{Rate: 2, Burst: 3}))
+	clock := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+	lg.limiter.now = func() time.Time { return clock }
+	for i := 0; i < 5; i++ {
+		lg.Error("failed", "i", i)
+		lg.Info("ok")
+	}
+	if len(h.records) != 8 {
+		t.Fatalf("rate limit did not suppress records beyond burst: %d", len(h.records))
+	}
+	clock = clock.Add(time.Second)
+	for i := 0; i < 3; i++ {
+		lg.Error("failed", "i", i)
+	}
+	rs := h.records[8:]
+	if len(rs) != 3 || rs[0].Message != "suppressed 2 ERROR messages" || rs[0].Fields[0] != F("suppressed", 2) {
+		t.Fatalf("rate limit did not refill tokens and summarize suppressed records: %+v", rs)
+	}
+	lg.Flush()
+	if rs := h.records[11:]; len(rs) != 1 || rs[0].Message != "suppressed 1 ERROR messages" {
+		t.Fatalf("Flush did not summarize records suppressed by rate limit: %+v", rs)
+	}
+}
@@ -0,0 +1,427 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter selects the records yielded by a Scanner.
+// Zero values of the filter select all records
+type Filter struct {
+	MinLevel Level     // the minimum level of the records
+	Since    time.Time // the earliest time of the records, inclusive
+	Until    time.Time // the latest time of the records, exclusive
+	Session  string    // the session of the records
+	Host     string    // the host of the records
+	Service  string    // the service of the records
+	Source   string    // a substring of the source of the records
+}
+
+// Match evaluates whether the record 'r' is selected by the filter
+func (f *Filter) Match(r *Record) bool {
+	switch {
+	case r.Level < f.MinLevel:
+	case !f.Since.IsZero() && r.Time.Before(f.Since):
+	case !f.Until.IsZero() && !r.Time.Before(f.Until):
+	case f.Session != "" && r.Session != f.Session:
+	case f.Host != "" && r.Host != f.Host:
+	case f.Service != "" && r.Service != f.Service:
+	case f.Source != "" && !strings.Contains(r.Source, f.Source):
+	default:
+		return true
+	}
+	return false
+}
+
+// ParseError is the error of a
+// log line which could not be parsed
+type ParseError struct {
+	File string // the path of the log file, if any
+	Line int    // the line number of the log line
+	Text string // the log line
+	Err  error  // the cause of the error
+}
+
+func (e *ParseError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("could not parse log line %s:%d: %v", e.File, e.Line, e.Err)
+	}
+	return fmt.Sprintf("could not parse log line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Scanner is an iterator of the typed
+// records of a log read from an io.Reader
+type Scanner struct {
+	r       *bufio.Reader     // the log reader
+	enc     encoder           // the format of delimited records
+	filter  Filter            // the filter of the records yielded
+	rec     *Record           // the current record
+	line    int               // the line number of the current record
+	off     int64             // the bytes of the log read
+	err     error             // the error which stopped the scanner
+	skip    func(*ParseError) // if set, lines which cannot be parsed are skipped and reported
+	maxOpen int               // the max log files open at once by a DirScanner, if set
+}
+
+// ScanOption configures a Scanner created with log.Scan
+type ScanOption func(*Scanner)
+
+// Scan creates a Scanner of the records of the log
+// read from 'r'. Lines in json format are parsed as
+// json records and other lines as delimited records
+// in the default log format, overidden by the options.
+// Lines of logfmt or template formats are only
+// parsed in that format
+func Scan(r io.Reader, opts ...ScanOption) *Scanner {
+	s := &Scanner{r: bufio.NewReader(r), enc: defaultEncoder}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// Scan creates a Scanner of the records of the log
+// read from 'r' in the format of the logger
+func (lg *Logger) Scan(r io.Reader, opts ...ScanOption) *Scanner {
+	lg.mu.Lock()
+	enc := lg.encoder()
+	lg.mu.Unlock()
+	return Scan(r, append([]ScanOption{func(s *Scanner) { s.enc = enc }}, opts...)...)
+}
+
+// ScanFormat sets the order and elements of the
+// delimited records read by the Scanner, including
+// LogStdFmt or LogLogfmt to read delimited or logfmt records
+func ScanFormat(f ...int) ScanOption {
+	return func(s *Scanner) {
+		ft := []int{}
+		for _, i := range f {
+			if _, ok := elNames[i]; ok {
+				ft = append(ft, i)
+			} else if i == LogJsonFmt || i == LogStdFmt || i == LogLogfmt {
+				s.enc.setFmt(i, nil)
+			}
+		}
+		if len(ft) > 0 {
+			s.enc.format = ft
+		}
+	}
+}
+
+// ScanDelim sets the delimiter between the
+// elements of the records read by the Scanner
+func ScanDelim(d string) ScanOption {
+	return func(s *Scanner) { s.enc.delim = d }
+}
+
+// ScanDateTimeFormat sets the datetime format
+// of the records read by the Scanner
+func ScanDateTimeFormat(f string) ScanOption {
+	return func(s *Scanner) { s.enc.timeFmt = f }
+}
+
+// ScanFilter sets the filter of
+// the records yielded by the Scanner
+func ScanFilter(f Filter) ScanOption {
+	return func(s *Scanner) { s.filter = f }
+}
+
+// ScanSkipInvalid skips the lines which cannot be
+// parsed in place of stopping the Scanner, calling 'f',
+// if not nil, with the error of each line skipped.
+// The errors of a DirScanner include the log file
+func ScanSkipInvalid(f func(err *ParseError)) ScanOption {
+	return func(s *Scanner) {
+		if s.skip = f; f == nil {
+			s.skip = func(*ParseError) {}
+		}
+	}
+}
+
+// Next advances the scanner to the next record
+// selected by its filter, which is then available
+// through Record. It returns false when the log
+// ends or a line cannot be parsed, unless invalid
+// lines are skipped with ScanSkipInvalid
+func (s *Scanner) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	for {
+		ln, err := s.r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			s.err = err
+			return false
+		}
+		if ln == "" && err == io.EOF {
+			return false
+		}
+		s.line++
+		s.off += int64(len(ln))
+		ln = strings.TrimRight(ln, "\r\n")
+		if ln != "" {
+			r, perr := s.enc.parse(ln)
+			if perr != nil && s.skip != nil {
+				s.skip(&ParseError{Line: s.line, Text: ln, Err: perr})
+			} else if perr != nil {
+				s.err = &ParseError{Line: s.line, Text: ln, Err: perr}
+				return false
+			} else if s.filter.Match(r) {
+				s.rec = r
+				return true
+			}
+		}
+		if err == io.EOF {
+			return false
+		}
+	}
+}
+
+// Record returns the current record of the scanner
+func (s *Scanner) Record() *Record {
+	return s.rec
+}
+
+// Err returns the error which stopped the scanner, if any
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+// parse parses the log line 'ln' to a record in the
+// template or logfmt format of the encoder, if any.
+// Otherwise lines in json format are parsed as json
+// records and other lines as delimited records
+func (e *encoder) parse(ln string) (*Record, error) {
+	switch {
+	case e.tmpl != nil:
+		return e.parseTemplate(ln)
+	case e.logfmt:
+		return e.parseLogfmt(ln)
+	case strings.HasPrefix(ln, "{"):
+		return e.parseJson(ln)
+	}
+	return e.parseStd(ln)
+}
+
+// parseJson parses the json log line 'ln' to a record
+func (e *encoder) parseJson(ln string) (*Record, error) {
+	d := json.NewDecoder(strings.NewReader(ln))
+	m := map[string]any{}
+	if err := d.Decode(&m); err != nil {
+		return nil, err
+	}
+	r := &Record{}
+	for k, v := range m {
+		if k == stackKey {
+			if r.Stack = stackOf(v); r.Stack != nil {
+				continue
+			}
+		}
+		s, isStr := v.(string)
+		if !isStr || !isElName(k) {
+			r.Fields = append(r.Fields, Field{strings.TrimPrefix(k, "fields."), v})
+			continue
+		}
+		if err := e.setElement(r, k, s); err != nil {
+			return nil, err
+		}
+	}
+	sortFields(r.Fields)
+	return r, nil
+}
+
+// parseStd parses the delimited log line 'ln' to a record
+func (e *encoder) parseStd(ln string) (*Record, error) {
+	vals := splitStd(ln, e.delim)
+	r := &Record{}
+	vals = e.parseCtxElements(r, vals)
+	if len(vals) < len(e.format) {
+		return nil, errors.New("missing elements of log format")
+	}
+	var extra []string
+	for _, f := range vals[len(e.format):] {
+		if k, v, ok := parseField(f); ok && k == stackKey {
+			r.Stack = strings.Split(v, "\n")
+		} else if ok {
+			r.Fields = append(r.Fields, Field{k, v})
+		} else {
+			extra = append(extra, f)
+		}
+	}
+	for i, el := range e.format {
+		v := vals[i]
+		if v == emptyEl {
+			continue
+		}
+		if strings.HasPrefix(v, `"`) {
+			u, err := strconv.Unquote(v)
+			if err != nil {
+				return nil, err
+			}
+			v = u
+		}
+		if err := e.setElement(r, elNames[el], v); err != nil {
+			return nil, err
+		}
+	}
+	if len(extra) > 0 {
+		// an unquoted message containing the delimiter
+		if e.format[len(e.format)-1] != LogMessage {
+			return nil, errors.New("unexpected elements after log format")
+		}
+		r.Message = strings.Join(append([]string{r.Message}, extra...), e.delim)
+	}
+	return r, nil
+}
+
+// parseCtxElements sets the trace and span ids of the
+// record 'r' of the key=value elements after the session
+// of the delimited values 'vals' of a log line, in the
+// manner of buildStdLog, and returns the other values
+func (e *encoder) parseCtxElements(r *Record, vals []string) []string {
+	for i, el := range e.format {
+		if el != LogSession {
+			continue
+		}
+		for _, id := range []int{LogTraceID, LogSpanID} {
+			if i+1 >= len(vals) || e.hasElement(id) {
+				continue
+			}
+			if k, v, ok := parseField(vals[i+1]); ok && k == elNames[id] {
+				e.setElement(r, k, v)
+				vals = append(vals[:i+1:i+1], vals[i+2:]...)
+			}
+		}
+		break
+	}
+	return vals
+}
+
+// setElement sets the element named 'k'
+// of the record 'r' to the value 'v'
+func (e *encoder) setElement(r *Record, k, v string) (err error) {
+	switch k {
+	case "level":
+		l, ok := levelByName(v)
+		if !ok {
+			return fmt.Errorf("invalid level %q", v)
+		}
+		r.Level = l
+	case "datetime":
+		r.Time, err = time.ParseInLocation(e.timeFmt, v, time.Local)
+	case "fullsource":
+		r.Source = v
+	case "source":
+		if r.Source == "" {
+			r.Source = v
+		}
+	case "session":
+		r.Session = v
+	case "host":
+		r.Host = v
+	case "service":
+		r.Service = v
+	case "message":
+		r.Message = v
+	case "trace":
+		r.TraceID = v
+	case "span":
+		r.SpanID = v
+	}
+	return
+}
+
+// stackOf converts the json value 'v' of a
+// stack trace to its frames, returning nil
+// if the value is not an array of frames
+func stackOf(v any) []string {
+	a, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+	s := make([]string, len(a))
+	for i, f := range a {
+		if s[i], ok = f.(string); !ok {
+			return nil
+		}
+	}
+	return s
+}
+
+// isElName evaluates whether 'k' is the name of an element
+func isElName(k string) bool {
+	for _, n := range elNames {
+		if n == k {
+			return true
+		}
+	}
+	return false
+}
+
+// sortFields sorts the fields 'fs' by key
+func sortFields(fs []Field) {
+	sort.Slice(fs, func(i, j int) bool { return fs[i].Key < fs[j].Key })
+}
+
+// toMap converts the record 'r' to a map of its
+// non-empty elements and fields, with the source
+// file and line as 'file' and 'line'
+func (r *Record) toMap() map[string]any {
+	m := map[string]any{"level": levelNames[r.Level]}
+	for k, v := range map[string]string{
+		"session": r.Session,
+		"host":    r.Host,
+		"service": r.Service,
+		"trace":   r.TraceID,
+		"span":    r.SpanID,
+		"message": r.Message,
+		"source":  r.Source,
+	} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	if !r.Time.IsZero() {
+		m["datetime"] = r.Time
+	}
+	if i := strings.LastIndex(r.Source, ":"); i > 0 {
+		m["file"] = r.Source[:i]
+		m["line"], _ = strconv.Atoi(r.Source[i+1:])
+	}
+	if len(r.Stack) > 0 {
+		m[stackKey] = r.Stack
+	}
+	for _, f := range r.Fields {
+		if _, ok := m[f.Key]; !ok {
+			m[f.Key] = f.Value
+		}
+	}
+	return m
+}
+
+// readAll returns the records of the log read
+// from 'r' in the format of the encoder 'e'
+func readAll(r io.Reader, e encoder) ([]map[string]any, error) {
+	m := []map[string]any{}
+	s := Scan(r, func(s *Scanner) { s.enc = e })
+	for s.Next() {
+		m = append(m, s.Record().toMap())
+	}
+	return m, s.Err()
+}
@@ -0,0 +1,113 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScanStd(t *testing.T) {
+	b := new(bytes.Buffer)
+	lg := New(WithWriter(b), WithSession("s1"), WithDelim(" "))
+	lg.Info("message with the delim", "note", "a b", "n", 2)
+	lg.Warning(`"quoted" - message`)
+	lg.Error("-")
+	s := lg.Scan(b)
+	var rs []Record
+	for s.Next() {
+		rs = append(rs, *s.Record())
+	}
+	if s.Err() != nil || len(rs) != 3 {
+		t.Fatalf("Scan did not parse records: %d records, err %v", len(rs), s.Err())
+	}
+	if r := rs[0]; r.Level != INFO || r.Message != "message with the delim" || r.Session != "s1" ||
+		len(r.Fields) != 2 || r.Fields[0] != F("note", "a b") || r.Fields[1] != F("n", "2") {
+		t.Fatalf("Scan did not parse record containing the delim: %+v", r)
+	}
+	if r := rs[0]; r.TraceID != "" || !strings.HasPrefix(r.Source, "scan_test.go:") || r.Time.IsZero() {
+		t.Fatalf("Scan did not parse record elements: %+v", r)
+	}
+	if rs[1].Message != `"quoted" - message` || rs[2].Message != "-" {
+		t.Fatalf("Scan did not parse quoted messages: %q, %q", rs[1].Message, rs[2].Message)
+	}
+}
+
+func TestScanLegacy(t *testing.T) {
+	ln := "INFO \t2022-01-02 03:04:05.000 \ts1 \tmain.go:12 \tmessage \twith delim\n"
+	s := Scan(strings.NewReader(ln), ScanFormat(LogLevel, LogDateTime, LogSession, LogSource, LogMessage))
+	if !s.Next() {
+		t.Fatal("Scan did not parse legacy record:", s.Err())
+	}
+	r := s.Record()
+	if r.Message != "message \twith delim" || r.Source != "main.go:12" || r.Time.Year() != 2022 {
+		t.Fatalf("Scan did not parse legacy record with unquoted delim: %+v", r)
+	}
+}
+
+func TestScanJson(t *testing.T) {
+	b := new(bytes.Buffer)
+	lg := New(WithWriter(b), WithSession("s1"), WithFormat(LogJsonFmt, LogLevel, LogDateTime, LogSession, LogFullSource, LogMessage))
+	lg.Info("json record", "level", "x", "n", 2)
+	s := Scan(b)
+	if !s.Next() {
+		t.Fatal("Scan did not parse json record:", s.Err())
+	}
+	r := s.Record()
+	if r.Level != INFO || r.Message != "json record" || !strings.Contains(r.Source, "/log/scan_test.go:") {
+		t.Fatalf("Scan did not parse json record elements: %+v", r)
+	}
+	if len(r.Fields) != 2 || r.Fields[0] != F("level", "x") || r.Fields[1] != F("n", float64(2)) {
+		t.Fatalf("Scan did not parse json record fields: %+v", r.Fields)
+	}
+}
+
+func TestScanFilter(t *testing.T) {
+	b := new(bytes.Buffer)
+	New(WithWriter(b), WithSession("s1")).Info("one")
+	New(WithWriter(b), WithSession("s2")).Warning("two")
+	New(WithWriter(b), WithSession("s2")).Error("three")
+	for _, tc := range []struct {
+		f   Filter
+		exp string
+	}{
+		{Filter{}, "one,two,three"},
+		{Filter{MinLevel: WARNING}, "two,three"},
+		{Filter{Session: "s2"}, "two,three"},
+		{Filter{Source: "scan_test.go", Until: time.Now().Add(-time.Hour)}, ""},
+		{Filter{Since: time.Now().Add(-time.Hour), MinLevel: ERROR}, "three"},
+	} {
+		var msgs []string
+		s := Scan(bytes.NewReader(b.Bytes()), ScanFilter(tc.f))
+		for s.Next() {
+			msgs = append(msgs, s.Record().Message)
+		}
+		if r := strings.Join(msgs, ","); r != tc.exp || s.Err() != nil {
+			t.Fatalf("Scan with filter %+v returned %q, expected %q", tc.f, r, tc.exp)
+		}
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	s := Scan(strings.NewReader("{\"level\":\"INFO\"}\nBAD \t- \t- \t- \tmsg\n"))
+	if !s.Next() || s.Next() {
+		t.Fatal("Scan did not stop at invalid record")
+	}
+	var pe *ParseError
+	if !errors.As(s.Err(), &pe) || pe.Line != 2 {
+		t.Fatalf("Scan did not return parse error of invalid record: %v", s.Err())
+	}
+	lg := New(WithDir(t.TempDir()), WithFile("missing.log"))
+	os.Remove(filepath.Join(lg.dir, lg.file))
+	if _, err := lg.Read(); err == nil {
+		t.Fatal("Read did not return error for missing log file")
+	}
+}
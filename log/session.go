@@ -0,0 +1,171 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SessionGenerator generates the session id of a
+// logger when it is activated, such as SessionUUIDv7
+type SessionGenerator func() string
+
+// sessionChars are the characters of the
+// random part of a SessionTime session id
+const sessionChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// SessionTime generates the default session id of the
+// time it is generated and 6 random characters, such
+// as '220101-150405-a1B2c3', which sorts by time
+func SessionTime() string {
+	return time.Now().Format(`060102-150405`) + "-" + rGen(6)
+}
+
+// SessionUUIDv4 generates a random
+// UUID version 4 session id
+func SessionUUIDv4() string {
+	return uuid.NewString()
+}
+
+// SessionUUIDv7 generates a UUID version 7
+// session id, which sorts by time
+func SessionUUIDv7() string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return uuid.NewString()
+	}
+	return id.String()
+}
+
+// crockford is the base32 alphabet of ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// SessionULID generates a ULID session id of 26
+// characters, which sorts by time in milliseconds
+func SessionULID() string {
+	var b [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	binary.BigEndian.PutUint16(b[:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:6], uint32(ms))
+	rand.Read(b[6:])
+	// encode the 128 bits in 26 groups of 5 bits,
+	// the first group holding the 3 leading bits
+	id := make([]byte, 26)
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	for i := 25; i >= 0; i-- {
+		id[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(id)
+}
+
+// rGen generates a string of 'l'
+// random alphanumeric characters,
+// rejecting the random bytes beyond the
+// largest multiple of the count of characters
+// so each character is equally likely
+func rGen(l int) string {
+	limit := 256 - 256%len(sessionChars)
+	s, b := make([]byte, 0, l), make([]byte, l)
+	for len(s) < l {
+		rand.Read(b)
+		for _, c := range b {
+			if int(c) < limit && len(s) < l {
+				s = append(s, sessionChars[int(c)%len(sessionChars)])
+			}
+		}
+	}
+	return string(s)
+}
+
+// WithSessionGenerator generates the session
+// id of a new Logger with the generator 'g'
+func WithSessionGenerator(g SessionGenerator) Option {
+	return func(lg *Logger) { lg.SetSessionGenerator(g) }
+}
+
+// SetSessionGenerator generates the session id of the
+// logger with the generator 'g', such as SessionUUIDv7,
+// SessionULID or a user-defined generator, unless the
+// session is set with SetSession. The session id is
+// generated when the logger is activated
+func (lg *Logger) SetSessionGenerator(g SessionGenerator) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.sessionGen = g
+	}
+}
+
+// SetSessionGenerator generates the session id
+// of the default logger with the generator 'g'
+func SetSessionGenerator(g SessionGenerator) {
+	Default().SetSessionGenerator(g)
+}
+
+// WithSessionHeader posts a header record of
+// the session of a new Logger when it is activated
+func WithSessionHeader(version string) Option {
+	return func(lg *Logger) { lg.SetSessionHeader(version) }
+}
+
+// SetSessionHeader posts an INFO header record as the
+// first record of the session of the logger, with the
+// process id, hostname, binary version, go version
+// and command-line args of the process. The binary
+// version is 'version' or, if empty, the version
+// of the main module in the build info of the binary
+// or '(devel)' if the binary has no version
+func (lg *Logger) SetSessionHeader(version string) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.header, lg.version = true, version
+	}
+}
+
+// SetSessionHeader posts a header record of
+// the session of the default logger
+func SetSessionHeader(version string) {
+	Default().SetSessionHeader(version)
+}
+
+// postHeader posts the header record of the
+// session of an activated logger, if any, and
+// if INFO records are enabled by the logger
+func (lg *Logger) postHeader() {
+	if !lg.header || !lg.Enabled(INFO) {
+		return
+	}
+	v := lg.version
+	if info, ok := debug.ReadBuildInfo(); ok && v == "" {
+		v = info.Main.Version
+	}
+	if v == "" {
+		v = "(devel)"
+	}
+	host, _ := os.Hostname()
+	lg.handle(&Record{
+		Level:   INFO,
+		Time:    time.Now(),
+		Message: "session started",
+		Fields: []Field{
+			{"pid", os.Getpid()},
+			{"hostname", host},
+			{"version", v},
+			{"go", runtime.Version()},
+			{"args", os.Args},
+		},
+	})
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"os"
+	"regexp"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSessionGenerators(t *testing.T) {
+	if s := SessionTime(); !regexp.MustCompile(`^\d{6}-\d{6}-[0-9A-Za-z]{6}$`).MatchString(s) {
+		t.Fatalf("SessionTime did not generate time session id: %s", s)
+	}
+	for _, g := range []struct {
+		gen     SessionGenerator
+		version uuid.Version
+	}{{SessionUUIDv4, 4}, {SessionUUIDv7, 7}} {
+		if id, err := uuid.Parse(g.gen()); err != nil || id.Version() != g.version {
+			t.Fatalf("session generator did not generate UUID version %d: %v", g.version, id)
+		}
+	}
+	a := SessionULID()
+	time.Sleep(2 * time.Millisecond)
+	b := SessionULID()
+	re := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+	if !re.MatchString(a) || !re.MatchString(b) || a[:10] >= b[:10] {
+		t.Fatalf("SessionULID did not generate time sorted ULIDs: %s %s", a, b)
+	}
+	// the timestamp of a ULID is its first 10 characters
+	ms := int64(0)
+	for _, c := range a[:10] {
+		for i := range crockford {
+			if rune(crockford[i]) == c {
+				ms = ms<<5 | int64(i)
+			}
+		}
+	}
+	if d := time.Since(time.UnixMilli(ms)); d < 0 || d > time.Minute {
+		t.Fatalf("SessionULID did not encode the time: %s", a)
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		if s := SessionTime(); seen[s] {
+			t.Fatal("SessionTime generated duplicate session id:", s)
+		} else {
+			seen[s] = true
+		}
+	}
+}
+
+func TestSessionGenerator(t *testing.T) {
+	h := &recordHandler{}
+	lg := New(WithHandler(h), WithSessionGenerator(func() string { return "custom-1" }))
+	lg.Info("first")
+	if lg.Session() != "custom-1" || h.records[0].Session != "custom-1" {
+		t.Fatalf("logger did not generate session with generator: %s", lg.Session())
+	}
+	lg = New(WithHandler(h), WithSession("set"), WithSessionGenerator(SessionULID))
+	lg.Info("first")
+	if lg.Session() != "set" {
+		t.Fatal("session generator overrode session set")
+	}
+	lg = New(WithHandler(h))
+	if err := lg.Configure(Config{SessionID: "uuidv7"}); err != nil {
+		t.Fatal("could not configure session generator:", err)
+	}
+	lg.Info("first")
+	if id, err := uuid.Parse(lg.Session()); err != nil || id.Version() != 7 {
+		t.Fatalf("Configure did not set session generator: %s", lg.Session())
+	}
+	if err := lg.Configure(Config{SessionID: "serial"}); err == nil {
+		t.Fatal("Configure did not return error of unknown session generator")
+	}
+}
+
+func TestSessionHeader(t *testing.T) {
+	h := &recordHandler{}
+	lg := New(WithHandler(h), WithSession("s1"), WithSessionHeader("v1.2.3"))
+	lg.Info("first")
+	lg.Info("second")
+	if len(h.records) != 3 || h.records[1].Message != "first" {
+		t.Fatalf("logger did not post one header record at session start: %+v", h.records)
+	}
+	r := h.records[0]
+	f := fieldMap(r)
+	host, _ := os.Hostname()
+	if r.Level != INFO || r.Message != "session started" || r.Session != "s1" || f["pid"] != os.Getpid() ||
+		f["hostname"] != host || f["version"] != "v1.2.3" || f["go"] != runtime.Version() || len(f["args"].([]string)) == 0 {
+		t.Fatalf("logger did not post session header record: %+v", r)
+	}
+	h = &recordHandler{}
+	lg = New(WithHandler(h), WithSessionHeader(""))
+	lg.Info("first")
+	if v := fieldMap(h.records[0])["version"]; v == "" {
+		t.Fatal("session header did not post version of build info")
+	}
+	if s := lg.Stats(); s.Records[INFO] != 2 {
+		t.Fatalf("logger did not count session header record: %+v", s)
+	}
+	h = &recordHandler{}
+	lg = New(WithHandler(h), WithSessionHeader(""), WithLevel(WARNING))
+	lg.Warning("first")
+	if len(h.records) != 1 {
+		t.Fatalf("logger posted session header below its level: %+v", h.records)
+	}
+	// hooks may read the stats of the logger posting the header
+	var stats Metrics
+	lg = New(WithHandler(&recordHandler{}), WithSessionHeader(""))
+	lg.AddHook(INFO, func(r *Record) { stats = lg.Stats() })
+	lg.Info("first")
+	if stats.Records[INFO] != 1 {
+		t.Fatalf("hook did not read stats of logger: %+v", stats)
+	}
+}
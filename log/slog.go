@@ -0,0 +1,191 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// slogFatal is the slog level mapped to FATAL
+const slogFatal = slog.LevelError + 4
+
+// fromSlogLevel converts a slog level to a logging Level
+func fromSlogLevel(l slog.Level) Level {
+	switch {
+	case l >= slogFatal:
+		return FATAL
+	case l >= slog.LevelError:
+		return ERROR
+	case l >= slog.LevelWarn:
+		return WARNING
+	case l >= slog.LevelInfo:
+		return INFO
+	}
+	return TRACE
+}
+
+// toSlogLevel converts a logging Level to a slog level
+func toSlogLevel(l Level) slog.Level {
+	switch l {
+	case TRACE:
+		return slog.LevelDebug
+	case INFO:
+		return slog.LevelInfo
+	case WARNING:
+		return slog.LevelWarn
+	case ERROR:
+		return slog.LevelError
+	}
+	return slogFatal
+}
+
+// slogHandler is a slog.Handler which posts
+// slog records to a Logger
+type slogHandler struct {
+	lg     *Logger // the logger posted records
+	group  string  // the prefix of the keys of the attrs, if any
+	fields []Field // the fields of the attrs bound to the handler
+}
+
+// NewSlogHandler creates a slog.Handler which posts
+// slog records to the logger 'lg', using the format,
+// session and log file of the logger. Attrs of a group
+// are posted as fields with keys prefixed by 'group.'
+func NewSlogHandler(lg *Logger) slog.Handler {
+	return &slogHandler{lg: lg}
+}
+
+// Enabled evaluates whether slog records of
+// level 'l' are posted to the logger
+func (h *slogHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return h.lg.Enabled(fromSlogLevel(l))
+}
+
+// Handle posts the slog record 'sr' to the logger
+// with the trace and span ids of the context 'ctx',
+// sampled and with the stack trace of its caller in
+// the manner of the records of the logger
+func (h *slogHandler) Handle(ctx context.Context, sr slog.Record) error {
+	r := &Record{
+		Level:   fromSlogLevel(sr.Level),
+		Time:    sr.Time,
+		Message: sr.Message,
+	}
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	depth := 0
+	if sr.PC != 0 {
+		f, _ := runtime.CallersFrames([]uintptr{sr.PC}).Next()
+		r.Source = fmt.Sprint(f.File, ":", f.Line)
+		depth = callerDepth(sr.PC)
+	}
+	r.Fields = make([]Field, 0, len(h.lg.fields)+len(h.fields)+sr.NumAttrs())
+	r.Fields = append(append(r.Fields, h.lg.fields...), h.fields...)
+	sr.Attrs(func(a slog.Attr) bool {
+		r.Fields = appendAttr(r.Fields, h.group, a)
+		return true
+	})
+	h.lg.emit(ctx, r, depth)
+	return nil
+}
+
+// callerDepth returns the levels above the caller of
+// callerDepth in the call stack of the caller whose
+// program counter is 'pc', as recorded by slog
+func callerDepth(pc uintptr) int {
+	pcs := make([]uintptr, maxStack)
+	n := runtime.Callers(3, pcs)
+	for i, p := range pcs[:n] {
+		if p == pc {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// WithAttrs returns a handler which posts
+// the attrs 'as' with every record
+func (h *slogHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	n := len(h.fields)
+	fs := h.fields[:n:n]
+	for _, a := range as {
+		fs = appendAttr(fs, h.group, a)
+	}
+	return &slogHandler{lg: h.lg, group: h.group, fields: fs}
+}
+
+// WithGroup returns a handler which prefixes the
+// keys of the attrs of records with the group 'g'
+func (h *slogHandler) WithGroup(g string) slog.Handler {
+	if g == "" {
+		return h
+	}
+	return &slogHandler{lg: h.lg, group: h.group + g + ".", fields: h.fields}
+}
+
+// appendAttr appends the attr 'a' to the fields 'fs'
+// using the key prefix 'group', flattening groups
+func appendAttr(fs []Field, group string, a slog.Attr) []Field {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			group += a.Key + "."
+		}
+		for _, ga := range v.Group() {
+			fs = appendAttr(fs, group, ga)
+		}
+		return fs
+	}
+	if a.Key == "" {
+		return fs
+	}
+	return append(fs, Field{group + a.Key, v.Any()})
+}
+
+// slogSink is a Handler which posts
+// records to a slog.Handler
+type slogSink struct {
+	h slog.Handler
+}
+
+// NewSlogSink creates a Handler which posts records
+// to the slog.Handler 'h'. The session, host, service,
+// source and trace ids of the records are posted as attrs
+func NewSlogSink(h slog.Handler) Handler {
+	return &slogSink{h}
+}
+
+// Enabled evaluates whether the slog.Handler
+// handles records of Level 'l'
+func (s *slogSink) Enabled(l Level) bool {
+	return s.h.Enabled(context.Background(), toSlogLevel(l))
+}
+
+// Handle posts the record 'r' to the slog.Handler
+func (s *slogSink) Handle(r *Record) error {
+	sr := slog.NewRecord(r.Time, toSlogLevel(r.Level), r.Message, 0)
+	for _, a := range [][2]string{
+		{"session", r.Session},
+		{"host", r.Host},
+		{"service", r.Service},
+		{"source", r.Source},
+		{"trace", r.TraceID},
+		{"span", r.SpanID},
+	} {
+		if a[1] != "" {
+			sr.AddAttrs(slog.String(a[0], a[1]))
+		}
+	}
+	for _, f := range r.Fields {
+		sr.AddAttrs(slog.Any(f.Key, f.Value))
+	}
+	return s.h.Handle(context.Background(), sr)
+}
@@ -0,0 +1,81 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	b := new(bytes.Buffer)
+	lg := New(WithWriter(b), WithSession("s1"), WithLevel(INFO),
+		WithFormat(LogJsonFmt, LogLevel, LogSession, LogTraceID, LogSource, LogMessage))
+	sl := slog.New(NewSlogHandler(lg.With("app", "a1"))).With("user", "u1").WithGroup("req")
+	sl.Debug("hidden")
+	ctx := ContextWithTrace(context.Background(), "t1", "")
+	sl.WarnContext(ctx, "slow", "ms", 12, slog.Group("db", "table", "orders"))
+	lg.Info("native")
+	lns := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lns) != 2 {
+		t.Fatalf("slog handler did not post records at logger level: %q", b)
+	}
+	m := map[string]any{}
+	if err := json.Unmarshal([]byte(lns[0]), &m); err != nil {
+		t.Fatal("could not unmarshal json log of slog record")
+	}
+	exp := map[string]any{
+		"level": "WARNING", "session": "s1", "trace": "t1", "message": "slow",
+		"app": "a1", "user": "u1", "req.ms": float64(12), "req.db.table": "orders",
+	}
+	for k, v := range exp {
+		if m[k] != v {
+			t.Fatalf("slog record posted %s as %v, expected %v", k, m[k], v)
+		}
+	}
+	if s, _ := m["source"].(string); !strings.HasPrefix(s, "slog_test.go:") {
+		t.Fatalf("slog record did not post source of caller: %v", m["source"])
+	}
+}
+
+func TestSlogHandlerSampling(t *testing.T) {
+	h := &recordHandler{}
+	lg := New(WithHandler(h), WithSampling(Sampling{First: 1}), WithStackTrace(ERROR))
+	sl := slog.New(NewSlogHandler(lg))
+	for i := 0; i < 3; i++ {
+		sl.Info("sampled")
+	}
+	sl.Error("failed")
+	NewSlogHandler(lg).Handle(context.Background(), slog.Record{Level: slog.LevelWarn, Message: "untimed"})
+	if len(h.records) != 3 || lg.Stats().Suppressed != 2 {
+		t.Fatalf("slog handler did not sample records: %+v", h.records)
+	}
+	if r := h.records[1]; len(r.Stack) == 0 || !strings.Contains(r.Stack[0], "TestSlogHandlerSampling") {
+		t.Fatalf("slog handler did not post stack trace of caller: %v", r.Stack)
+	}
+	if r := h.records[2]; r.Time.IsZero() || r.Level != WARNING {
+		t.Fatalf("slog handler did not time record without time: %+v", r)
+	}
+}
+
+func TestSlogSink(t *testing.T) {
+	b := new(bytes.Buffer)
+	sh := slog.NewJSONHandler(b, &slog.HandlerOptions{Level: slog.LevelInfo})
+	lg := New(WithHandler(NewSlogSink(sh)), WithSession("s1"))
+	lg.Trace("hidden")
+	lg.Error("failed", "code", 7)
+	m := map[string]any{}
+	if err := json.Unmarshal(b.Bytes(), &m); err != nil {
+		t.Fatalf("slog sink did not post record to slog handler: %q", b)
+	}
+	if m["level"] != "ERROR" || m["msg"] != "failed" || m["session"] != "s1" || m["code"] != float64(7) {
+		t.Fatalf("slog sink did not post record elements as attrs: %v", m)
+	}
+}
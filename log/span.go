@@ -0,0 +1,193 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+)
+
+// field keys of the records of spans
+const (
+	parentKey   = "parent"   // the span id of the parent span
+	durationKey = "duration" // the elapsed time of the span
+	outcomeKey  = "outcome"  // the outcome of the span, ok or error
+	slowKey     = "slow"     // the slow threshold exceeded by the span
+)
+
+// Span is a timed operation of a logger started with
+// Start, which posts a record of the operation with its
+// duration and outcome when it ends. The record of a
+// span is posted with the span id and the trace id of
+// its root span, and the span id of its parent, so the
+// tree of operations can be rebuilt from the log
+//
+//	span := log.Start("import batch", "file", name)
+//	n, err := importBatch(name)
+//	span.End(err, "rows", n)
+type Span struct {
+	lg     *Logger
+	ctx    context.Context // the context of the span carrying its ids
+	name   string          // the name of the operation
+	id     string          // the span id
+	parent string          // the span id of the parent span, if any
+	start  time.Time       // the time the span started
+	slow   time.Duration   // the slow threshold of the span, if any
+	fields []Field         // the key/value fields of the span
+	ended  uint32          // if 1, the span has ended
+}
+
+// start starts a span named 'name' with the
+// key/value fields 'kv' and the parent span and
+// trace of the context 'ctx', if any
+func (lg *Logger) start(ctx context.Context, name string, kv []any) *Span {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	trace, parent := TraceFromContext(ctx)
+	s := &Span{
+		lg:     lg,
+		name:   name,
+		id:     spanID(),
+		parent: parent,
+		slow:   time.Duration(atomic.LoadInt64(&lg.slow)),
+		fields: fieldsOf(kv),
+	}
+	if trace == "" {
+		trace = s.id
+	}
+	s.ctx = ContextWithTrace(ctx, trace, s.id)
+	s.start = time.Now()
+	return s
+}
+
+// Start starts a span named 'name' with
+// the key/value fields 'kv' posted by the
+// logger when the span ends
+func (lg *Logger) Start(name string, kv ...any) *Span {
+	return lg.start(context.Background(), name, kv)
+}
+
+// StartCtx starts a span named 'name' with the key/value
+// fields 'kv', whose parent is the span of the context
+// 'ctx', if any, and returns a copy of the context
+// carrying the span for the spans and records of the
+// operation
+func (lg *Logger) StartCtx(ctx context.Context, name string, kv ...any) (context.Context, *Span) {
+	s := lg.start(ctx, name, kv)
+	return s.ctx, s
+}
+
+// Start starts a span named 'name' with the key/value
+// fields 'kv' posted by the default logger
+func Start(name string, kv ...any) *Span {
+	return Default().start(context.Background(), name, kv)
+}
+
+// StartCtx starts a span named 'name' of the logger
+// carried by the context 'ctx', whose parent is the
+// span of the context, if any
+func StartCtx(ctx context.Context, name string, kv ...any) (context.Context, *Span) {
+	s := FromContext(ctx).start(ctx, name, kv)
+	return s.ctx, s
+}
+
+// Start starts a span named 'name' with the
+// key/value fields 'kv' nested in the span
+func (s *Span) Start(name string, kv ...any) *Span {
+	return s.lg.start(s.ctx, name, kv)
+}
+
+// Slow sets the threshold of the duration of the
+// span above which it is posted as a WARNING record
+func (s *Span) Slow(d time.Duration) *Span {
+	s.slow = d
+	return s
+}
+
+// ID returns the span id of the span
+func (s *Span) ID() string {
+	return s.id
+}
+
+// Context returns a copy of the context 'ctx' carrying
+// the span and trace ids of the span, which are posted
+// with the records of the context log functions
+func (s *Span) Context(ctx context.Context) context.Context {
+	trace, _ := TraceFromContext(s.ctx)
+	return ContextWithTrace(ctx, trace, s.id)
+}
+
+// End ends the span and posts its record with the
+// key/value fields 'kv', its duration and outcome.
+// The record is an ERROR record if 'err' is not nil,
+// a WARNING record if the span exceeded its slow
+// threshold and otherwise an INFO record. End returns
+// the duration of the span and only posts its record once
+func (s *Span) End(err error, kv ...any) time.Duration {
+	d := time.Since(s.start)
+	if !atomic.CompareAndSwapUint32(&s.ended, 0, 1) {
+		return d
+	}
+	l, fs := INFO, append(s.fields[:len(s.fields):len(s.fields)], fieldsOf(kv)...)
+	if s.parent != "" {
+		fs = append(fs, F(parentKey, s.parent))
+	}
+	fs = append(fs, F(durationKey, d))
+	switch {
+	case err != nil:
+		l = ERROR
+		fs = append(fs, F(outcomeKey, "error"), Err(err))
+	case s.slow > 0 && d > s.slow:
+		l = WARNING
+		fs = append(fs, F(outcomeKey, "ok"), F(slowKey, s.slow))
+	default:
+		fs = append(fs, F(outcomeKey, "ok"))
+	}
+	s.lg.outputCtx(s.ctx, 2, l, s.name, fieldArgs(fs)...)
+	return d
+}
+
+// fieldArgs returns the fields 'fs' as
+// key/value arguments of a log function
+func fieldArgs(fs []Field) []any {
+	kv := make([]any, len(fs))
+	for i, f := range fs {
+		kv[i] = f
+	}
+	return kv
+}
+
+// spanID generates a random span id
+// of 16 hexadecimal characters
+func spanID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithSlowThreshold sets the duration above which
+// the spans of a new Logger are posted as WARNING records
+func WithSlowThreshold(d time.Duration) Option {
+	return func(lg *Logger) { lg.SetSlowThreshold(d) }
+}
+
+// SetSlowThreshold sets the duration above which the
+// spans of the logger are posted as WARNING records,
+// unless overidden by Span.Slow, 0 for no threshold.
+// The threshold may be changed while the logger is active
+func (lg *Logger) SetSlowThreshold(d time.Duration) {
+	atomic.StoreInt64(&lg.slow, int64(d))
+}
+
+// SetSlowThreshold sets the duration above which the
+// spans of the default logger are posted as WARNING records
+func SetSlowThreshold(d time.Duration) {
+	Default().SetSlowThreshold(d)
+}
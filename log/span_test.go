@@ -0,0 +1,79 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fieldMap returns the fields of the record 'r' by key
+func fieldMap(r Record) map[string]any {
+	m := map[string]any{}
+	for _, f := range r.Fields {
+		m[f.Key] = f.Value
+	}
+	return m
+}
+
+func TestSpan(t *testing.T) {
+	h := &recordHandler{}
+	lg := New(WithHandler(h), WithSlowThreshold(time.Hour))
+	root := lg.With("request", "r1").Start("import batch", "file", "a.csv")
+	child := root.Start("parse rows")
+	child.End(nil, "rows", 3)
+	slow := root.Start("write rows").Slow(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	slow.End(nil)
+	d := root.End(errors.New("disk full"))
+	if root.End(nil) < d || len(h.records) != 3 {
+		t.Fatalf("spans did not post a record when ended: %d records", len(h.records))
+	}
+	c, s, r := h.records[0], h.records[1], h.records[2]
+	if c.Level != INFO || c.Message != "parse rows" || fieldMap(c)["rows"] != 3 || fieldMap(c)[outcomeKey] != "ok" {
+		t.Fatalf("span did not post record of operation: %+v", c)
+	}
+	if s.Level != WARNING || fieldMap(s)[slowKey] != time.Nanosecond {
+		t.Fatalf("span did not post WARNING record above slow threshold: %+v", s)
+	}
+	rf := fieldMap(r)
+	if r.Level != ERROR || rf[outcomeKey] != "error" || rf["request"] != "r1" || rf["file"] != "a.csv" ||
+		rf[errKey].(error).Error() != "disk full" || rf[durationKey].(time.Duration) != d {
+		t.Fatalf("span did not post ERROR record of failed operation: %+v", r)
+	}
+	if _, ok := rf[parentKey]; ok || r.SpanID != root.ID() || r.TraceID != root.ID() {
+		t.Fatalf("root span did not post its span id as trace id: %+v", r)
+	}
+	for _, n := range []Record{c, s} {
+		if fieldMap(n)[parentKey] != root.ID() || n.TraceID != root.ID() || n.SpanID == root.ID() {
+			t.Fatalf("nested span did not post id of parent span: %+v", n)
+		}
+	}
+	if !strings.HasSuffix(strings.Split(c.Source, ":")[0], "span_test.go") {
+		t.Fatalf("span did not post source of End call: %s", c.Source)
+	}
+}
+
+func TestSpanCtx(t *testing.T) {
+	b := new(bytes.Buffer)
+	lg := New(WithWriter(b), WithFormat(LogLevel, LogTraceID, LogSpanID, LogMessage), WithDelim("|"))
+	ctx := ContextWithTrace(NewContext(context.Background(), lg), "t1", "s0")
+	ctx, span := StartCtx(ctx, "handle")
+	InfoCtx(ctx, "inside")
+	_, child := StartCtx(ctx, "query")
+	child.End(nil)
+	span.End(nil)
+	id, cid := span.ID(), child.ID()
+	exp := "INFO|t1|" + id + "|inside\n" +
+		"INFO|t1|" + cid + "|query|parent=" + id + "|duration="
+	if r := b.String(); !strings.HasPrefix(r, exp) || !strings.Contains(r, "INFO|t1|"+id+"|handle|parent=s0|duration=") {
+		t.Fatalf("spans did not post ids of context: %q", r)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Metrics is a snapshot of the counters of a logger
+// and the loggers derived from it returned by Stats
+type Metrics struct {
+	Records     [FATAL + 1]uint64 // the records posted by level
+	Bytes       uint64            // the bytes written to the log file and console
+	WriteErrors uint64            // the errors writing or handling records
+	Dropped     uint64            // the records dropped by async writers and handlers
+	Suppressed  uint64            // the records suppressed by sampling and rate limits
+	LastError   string            // the last error writing or handling a record, if any
+}
+
+// Hook is called with each record of its level
+// before the record is written to the log. Hooks
+// may inspect the record but must not retain it
+// or post records to the logger
+type Hook func(r *Record)
+
+// levelHooks are the hooks of a logger by level
+type levelHooks [FATAL + 1][]Hook
+
+// counters are the counters of a logger
+type counters struct {
+	records    [FATAL + 1]uint64 // the records posted by level
+	bytes      uint64            // the bytes written
+	errors     uint64            // the write errors
+	suppressed uint64            // the records suppressed by the limiter
+	lastErr    atomic.Value      // the last write error as a writeErr
+}
+
+// writeErr wraps the errors stored by counters,
+// which must be of the same concrete type
+type writeErr struct{ error }
+
+// dropper is a handler which
+// counts the records it drops
+type dropper interface {
+	Dropped() uint64
+}
+
+// countWriter is an io.Writer which counts the bytes
+// written and write errors of the writer of a logger
+type countWriter struct {
+	w  io.Writer
+	lg *Logger
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	atomic.AddUint64(&c.lg.stats.bytes, uint64(n))
+	if err != nil {
+		c.lg.writeError(err)
+	}
+	return n, err
+}
+
+// writeError counts the error 'err' writing or handling
+// a record and calls the error handler of the logger
+func (lg *Logger) writeError(err error) {
+	atomic.AddUint64(&lg.stats.errors, 1)
+	lg.stats.lastErr.Store(writeErr{err})
+	if lg.onError != nil {
+		lg.onError(err)
+	}
+}
+
+// Stats returns a snapshot of the counters of the logger
+func (lg *Logger) Stats() Metrics {
+	s := Metrics{
+		Bytes:       atomic.LoadUint64(&lg.stats.bytes),
+		WriteErrors: atomic.LoadUint64(&lg.stats.errors),
+		Suppressed:  atomic.LoadUint64(&lg.stats.suppressed),
+	}
+	for l := range s.Records {
+		s.Records[l] = atomic.LoadUint64(&lg.stats.records[l])
+	}
+	if e, ok := lg.stats.lastErr.Load().(writeErr); ok {
+		s.LastError = e.Error()
+	}
+	lg.mu.Lock()
+	a, ca, h := lg.async, lg.consoleAsync, lg.handler
+	lg.mu.Unlock()
+	for _, w := range []*asyncWriter{a, ca} {
+		if w != nil {
+			s.Dropped += w.Dropped()
+		}
+	}
+	if d, ok := h.(dropper); ok {
+		s.Dropped += d.Dropped()
+	}
+	return s
+}
+
+// Stats returns a snapshot of the
+// counters of the default logger
+func Stats() Metrics {
+	return Default().Stats()
+}
+
+// expvarMu serializes the publishing of expvars
+// so that a name is checked and published at once
+var expvarMu sync.Mutex
+
+// PublishExpvar publishes the counters of the logger
+// as the expvar 'name', served as json by the expvar
+// handler at /debug/vars. It returns an error if
+// the name is already published
+func (lg *Logger) PublishExpvar(name string) error {
+	expvarMu.Lock()
+	defer expvarMu.Unlock()
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("expvar %q is already published", name)
+	}
+	expvar.Publish(name, expvar.Func(func() any {
+		s := lg.Stats()
+		recs := map[string]uint64{}
+		for l, n := range s.Records {
+			recs[levelNames[l]] = n
+		}
+		return map[string]any{
+			"records":      recs,
+			"bytes":        s.Bytes,
+			"write_errors": s.WriteErrors,
+			"dropped":      s.Dropped,
+			"suppressed":   s.Suppressed,
+			"last_error":   s.LastError,
+		}
+	}))
+	return nil
+}
+
+// PublishExpvar publishes the counters of
+// the default logger as the expvar 'name'
+func PublishExpvar(name string) error {
+	return Default().PublishExpvar(name)
+}
+
+// WithHook calls the hook 'h' with each record
+// of Level 'l' posted by a new Logger
+func WithHook(l Level, h Hook) Option {
+	return func(lg *Logger) { lg.AddHook(l, h) }
+}
+
+// AddHook calls the hook 'h' with each record of
+// Level 'l' posted by the logger, before the record
+// is written, such as to count alerts of ERROR records.
+// Hooks are called in the order they are added
+func (lg *Logger) AddHook(l Level, h Hook) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() && l <= FATAL {
+		lg.hooks[l] = append(lg.hooks[l], h)
+	}
+}
+
+// AddHook calls the hook 'h' with each record
+// of Level 'l' posted by the default logger
+func AddHook(l Level, h Hook) {
+	Default().AddHook(l, h)
+}
+
+// WithErrorHandler calls 'f' with the errors
+// writing or handling the records of a new Logger
+func WithErrorHandler(f func(err error)) Option {
+	return func(lg *Logger) { lg.SetErrorHandler(f) }
+}
+
+// SetErrorHandler calls 'f' with each error writing
+// or handling a record of the logger, which are
+// otherwise only counted in Stats. Records written
+// asynchronously report their errors from the
+// background writer. 'f' must not post to the logger
+func (lg *Logger) SetErrorHandler(f func(err error)) {
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if !lg.isActive() {
+		lg.onError = f
+	}
+}
+
+// SetErrorHandler calls 'f' with the errors writing
+// or handling the records of the default logger
+func SetErrorHandler(f func(err error)) {
+	Default().SetErrorHandler(f)
+}
@@ -0,0 +1,126 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// failWriter is a writer which fails every write
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestStats(t *testing.T) {
+	b := new(bytes.Buffer)
+	alerts := 0
+	lg := New(WithWriter(b), WithFormat(LogLevel, LogMessage), WithSampling(Sampling{First: 1}),
+		WithHook(ERROR, func(r *Record) { alerts++ }))
+	for i := 0; i < 3; i++ {
+		lg.Info("sampled")
+	}
+	lg.Warning("w")
+	lg.With("k", "v").Error("e1")
+	lg.Error("e2")
+	s := lg.Stats()
+	if s.Records != [FATAL + 1]uint64{0, 1, 1, 2, 0} || s.Suppressed != 2 || alerts != 2 {
+		t.Fatalf("logger did not count records: %+v alerts %d", s, alerts)
+	}
+	if s.Bytes != uint64(b.Len()) || s.WriteErrors != 0 || s.LastError != "" || s.Dropped != 0 {
+		t.Fatalf("logger did not count bytes written: %+v", s)
+	}
+	lg = New(WithWriter(b), WithAsync(4, Block))
+	lg.Info("written")
+	lg.Close()
+	lg.Info("dropped")
+	if s := lg.Stats(); s.Records[INFO] != 2 || s.Dropped != 1 {
+		t.Fatalf("logger did not count records dropped: %+v", s)
+	}
+	h := NewHTTPHandler("http://127.0.0.1:0")
+	h.Close()
+	lg = New(WithHandler(MultiHandler(&recordHandler{}, h)))
+	lg.Info("dropped")
+	if s := lg.Stats(); s.Dropped != 1 {
+		t.Fatalf("logger did not count records dropped by multi handler: %+v", s)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	var errs []error
+	lg := New(WithWriter(failWriter{}), WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	lg.Info("lost")
+	lg.Warning("lost")
+	s := lg.Stats()
+	if s.WriteErrors != 2 || s.LastError != "disk full" || len(errs) != 2 || s.Bytes != 0 {
+		t.Fatalf("logger did not surface write errors: %+v %v", s, errs)
+	}
+	h := &errHandler{}
+	lg = New(WithHandler(h))
+	lg.Info("lost")
+	if s := lg.Stats(); s.WriteErrors != 1 || s.LastError != "handler down" {
+		t.Fatalf("logger did not count handler errors: %+v", s)
+	}
+}
+
+// errHandler is a handler which fails every record
+type errHandler struct{}
+
+func (errHandler) Enabled(l Level) bool {
+	return true
+}
+
+func (errHandler) Handle(r *Record) error {
+	return errors.New("handler down")
+}
+
+// expvars counts the expvars published by the
+// tests, which must be unique across test runs
+var expvars uint32
+
+func TestPublishExpvar(t *testing.T) {
+	name := fmt.Sprintf("%s_%d", t.Name(), atomic.AddUint32(&expvars, 1))
+	lg := New(WithWriter(new(bytes.Buffer)))
+	if err := lg.PublishExpvar(name); err != nil {
+		t.Fatal("could not publish expvar of logger:", err)
+	}
+	if err := lg.PublishExpvar(name); err == nil {
+		t.Fatal("logger published expvar twice")
+	}
+	lg.Error("e")
+	m := map[string]any{}
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &m); err != nil {
+		t.Fatal("could not unmarshal expvar of logger:", err)
+	}
+	if m["records"].(map[string]any)["ERROR"] != float64(1) || m["bytes"].(float64) == 0 {
+		t.Fatalf("expvar did not publish counters of logger: %v", m)
+	}
+	// concurrent publishes of a name fail without panicking
+	name = fmt.Sprintf("%s_%d", t.Name(), atomic.AddUint32(&expvars, 1))
+	var wg sync.WaitGroup
+	var published uint32
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if lg.PublishExpvar(name) == nil {
+				atomic.AddUint32(&published, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if published != 1 {
+		t.Fatalf("logger published expvar %d times", published)
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Facility is the syslog facility of
+// the messages of a SyslogHandler
+type Facility uint
+
+const (
+	Kern   Facility = 0  // kernel messages
+	User   Facility = 1  // user-level messages, the default
+	Daemon Facility = 3  // system daemons
+	Local0 Facility = 16 // local use 0
+	Local1 Facility = 17 // local use 1
+	Local2 Facility = 18 // local use 2
+	Local3 Facility = 19 // local use 3
+	Local4 Facility = 20 // local use 4
+	Local5 Facility = 21 // local use 5
+	Local6 Facility = 22 // local use 6
+	Local7 Facility = 23 // local use 7
+)
+
+// syslogSeverities are the syslog severities of the levels
+var syslogSeverities = []int{
+	TRACE:   7, // debug
+	INFO:    6, // informational
+	WARNING: 4, // warning
+	ERROR:   3, // error
+	FATAL:   2, // critical
+}
+
+// syslogSDID is the id of the structured data
+// element of the session and fields of a record
+const syslogSDID = "gosimple@32473"
+
+// syslogTimeFmt is the timestamp format of syslog messages
+const syslogTimeFmt = "2006-01-02T15:04:05.000000Z07:00"
+
+// defaultSyslogTimeout is the default timeout of
+// the connections and writes of a SyslogHandler
+const defaultSyslogTimeout = 5 * time.Second
+
+// SyslogHandler is a Handler which sends records as
+// RFC 5424 syslog messages over UDP, TCP or a Unix socket.
+// The host and service of a record are sent as the hostname
+// and app-name of the message and the session, trace ids,
+// source and fields as its structured data
+type SyslogHandler struct {
+	mu       sync.Mutex
+	network  string        // the network of the syslog server
+	addr     string        // the address of the syslog server
+	conn     net.Conn      // the connection to the syslog server
+	facility Facility      // the facility of the messages
+	level    Level         // the minimum level sent by the handler
+	hostname string        // the hostname of records without a host
+	appName  string        // the app-name of records without a service
+	pid      string        // the process id of the messages
+	timeout  time.Duration // the timeout of the connections and writes
+}
+
+// SyslogOption configures a SyslogHandler
+// created with log.NewSyslogHandler
+type SyslogOption func(*SyslogHandler)
+
+// NewSyslogHandler creates a handler sending records
+// to the syslog server at the address 'addr' of the
+// network 'network', either "udp", "tcp", "unix" or
+// "unixgram" such as the local socket "/dev/log" read
+// by syslog daemons and journald. Messages are framed
+// by octet counting over stream networks
+func NewSyslogHandler(network, addr string, opts ...SyslogOption) (*SyslogHandler, error) {
+	h := &SyslogHandler{
+		network:  network,
+		addr:     addr,
+		facility: User,
+		pid:      strconv.Itoa(os.Getpid()),
+		appName:  filepath.Base(os.Args[0]),
+		timeout:  defaultSyslogTimeout,
+	}
+	h.hostname, _ = os.Hostname()
+	for _, o := range opts {
+		o(h)
+	}
+	if err := h.connect(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// SyslogFacility sets the facility
+// of the messages sent by the handler
+func SyslogFacility(f Facility) SyslogOption {
+	return func(h *SyslogHandler) { h.facility = f }
+}
+
+// SyslogLevel sets the minimum level
+// of the records sent by the handler
+func SyslogLevel(l Level) SyslogOption {
+	return func(h *SyslogHandler) { h.level = l }
+}
+
+// SyslogHostname sets the hostname of the messages
+// of records without a host, the hostname of the
+// machine by default
+func SyslogHostname(n string) SyslogOption {
+	return func(h *SyslogHandler) { h.hostname = n }
+}
+
+// SyslogAppName sets the app-name of the messages
+// of records without a service, the name of the
+// executable by default
+func SyslogAppName(n string) SyslogOption {
+	return func(h *SyslogHandler) { h.appName = n }
+}
+
+// SyslogTimeout sets the timeout of the connections
+// to the syslog server and of the writes of messages,
+// 5s by default, so that a server which stops reading
+// does not block the logger
+func SyslogTimeout(d time.Duration) SyslogOption {
+	return func(h *SyslogHandler) { h.timeout = d }
+}
+
+// Enabled evaluates whether records of
+// Level 'l' are sent by the handler
+func (h *SyslogHandler) Enabled(l Level) bool {
+	return l >= h.level
+}
+
+// Handle formats the record 'r' as a syslog message
+// and sends it to the syslog server, reconnecting
+// once if the connection was lost
+func (h *SyslogHandler) Handle(r *Record) error {
+	msg := h.format(r)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conn == nil {
+		if err := h.connect(); err != nil {
+			return err
+		}
+	}
+	if err := h.write(msg); err == nil {
+		return nil
+	}
+	h.conn.Close()
+	if err := h.connect(); err != nil {
+		return err
+	}
+	return h.write(msg)
+}
+
+// Close closes the connection to the syslog server
+func (h *SyslogHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conn == nil {
+		return nil
+	}
+	err := h.conn.Close()
+	h.conn = nil
+	return err
+}
+
+// connect connects the handler to the syslog
+// server within the timeout of the handler
+func (h *SyslogHandler) connect() error {
+	c, err := net.DialTimeout(h.network, h.addr, h.timeout)
+	if err != nil {
+		h.conn = nil
+		return err
+	}
+	h.conn = c
+	return nil
+}
+
+// write sends the message 'msg' to the syslog server
+// within the timeout of the handler, framed by its
+// length over stream networks
+func (h *SyslogHandler) write(msg []byte) error {
+	if h.network == "tcp" || h.network == "tcp4" || h.network == "tcp6" || h.network == "unix" {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+	if err := h.conn.SetWriteDeadline(time.Now().Add(h.timeout)); err != nil {
+		return err
+	}
+	_, err := h.conn.Write(msg)
+	return err
+}
+
+// format formats the record 'r' as an RFC 5424 message:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+func (h *SyslogHandler) format(r *Record) []byte {
+	pri := int(h.facility)*8 + syslogSeverities[r.Level]
+	host, app := r.Host, r.Service
+	if host == "" {
+		host = h.hostname
+	}
+	if app == "" {
+		app = h.appName
+	}
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "<%d>1 %s %s %s %s - ", pri, t.Format(syslogTimeFmt),
+		syslogName(host, 255), syslogName(app, 48), syslogName(h.pid, 128))
+	b.WriteString(syslogData(r))
+	if r.Message != "" {
+		b.WriteByte(' ')
+		b.WriteString(r.Message)
+	}
+	return []byte(b.String())
+}
+
+// syslogData formats the session, trace ids, source and
+// fields of the record 'r' as an SD element of params
+func syslogData(r *Record) string {
+	var ps []string
+	for _, p := range [][2]string{
+		{"session", r.Session},
+		{"trace", r.TraceID},
+		{"span", r.SpanID},
+		{"source", r.Source},
+	} {
+		if p[1] != "" {
+			ps = append(ps, syslogParam(p[0], p[1]))
+		}
+	}
+	for _, f := range r.Fields {
+		ps = append(ps, syslogParam(f.Key, fmt.Sprint(f.value(syslogTimeFmt))))
+	}
+	if len(r.Stack) > 0 {
+		ps = append(ps, syslogParam(stackKey, strings.Join(r.Stack, "\n")))
+	}
+	if len(ps) == 0 {
+		return "-"
+	}
+	return "[" + syslogSDID + " " + strings.Join(ps, " ") + "]"
+}
+
+// syslogParam formats the SD param of the name
+// 'k' and value 'v', escaping '"', '\' and ']'
+func syslogParam(k, v string) string {
+	k = strings.Map(func(c rune) rune {
+		if c <= ' ' || c > '~' || c == '=' || c == ']' || c == '"' {
+			return '_'
+		}
+		return c
+	}, k)
+	if len(k) > 32 {
+		k = k[:32]
+	}
+	v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(v)
+	return k + `="` + v + `"`
+}
+
+// syslogName formats the header field 'n' of a
+// syslog message as at most 'max' printable ascii
+// characters, or '-' if the field is empty
+func syslogName(n string, max int) string {
+	if n == "" {
+		return "-"
+	}
+	n = strings.Map(func(c rune) rune {
+		if c <= ' ' || c > '~' {
+			return '_'
+		}
+		return c
+	}, n)
+	if len(n) > max {
+		n = n[:max]
+	}
+	return n
+}
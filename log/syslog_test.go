@@ -0,0 +1,149 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package log
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var syslogRecord = Record{
+	Level:   WARNING,
+	Time:    time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC),
+	Source:  "main.go:12",
+	Session: "s1",
+	Host:    "web 1",
+	Service: "api",
+	Message: "slow request",
+	Fields:  []Field{F("path", `/a"b]`), F("took", 2*time.Second)},
+}
+
+func syslogMsg() string {
+	return "<164>1 2022-01-01T10:00:00.000000Z web_1 api " + strconv.Itoa(os.Getpid()) +
+		` - [gosimple@32473 session="s1" source="main.go:12" path="/a\"b\]" took="2s"] slow request`
+}
+
+func TestSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("could not listen on loopback:", err)
+	}
+	defer pc.Close()
+	h, err := NewSyslogHandler("udp", pc.LocalAddr().String(), SyslogFacility(Local4))
+	if err != nil {
+		t.Fatal("could not create syslog handler:", err)
+	}
+	defer h.Close()
+	if err := h.Handle(&syslogRecord); err != nil {
+		t.Fatal("could not send syslog message:", err)
+	}
+	b := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(b)
+	if err != nil || string(b[:n]) != syslogMsg() {
+		t.Fatalf("syslog handler did not send RFC 5424 message: %q %v", b[:n], err)
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("could not listen on loopback:", err)
+	}
+	defer ln.Close()
+	msgs := make(chan string, 2)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		r := bufio.NewReader(c)
+		for {
+			l, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(l))
+			b := make([]byte, n)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return
+			}
+			msgs <- string(b)
+		}
+	}()
+	lg := New(WithSession("s2"), WithHandler(mustSyslog(t, "tcp", ln.Addr().String(), SyslogHostname("h"), SyslogAppName("app"))))
+	lg.Error("failed")
+	lg.Info("ok")
+	for _, exp := range []string{`<11>1 `, `<14>1 `} {
+		select {
+		case m := <-msgs:
+			if !strings.HasPrefix(m, exp) || !strings.Contains(m, ` h app `) || !strings.Contains(m, `[gosimple@32473 session="s2" source="`) {
+				t.Fatalf("syslog handler did not send framed message: %q", m)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("syslog handler did not send message over tcp")
+		}
+	}
+}
+
+func TestSyslogUnix(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "log.sock")
+	pc, err := net.ListenPacket("unixgram", addr)
+	if err != nil {
+		t.Skip("could not listen on unix socket:", err)
+	}
+	defer pc.Close()
+	h := mustSyslog(t, "unixgram", addr, SyslogLevel(INFO))
+	defer h.Close()
+	if h.Enabled(TRACE) || !h.Enabled(INFO) {
+		t.Fatal("syslog handler not enabled at its level")
+	}
+	r := Record{Level: FATAL, Message: "down"}
+	h.Handle(&r)
+	b := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(b)
+	if err != nil || !strings.HasPrefix(string(b[:n]), "<10>1 ") || !strings.HasSuffix(string(b[:n]), " - - down") {
+		t.Fatalf("syslog handler did not send message over unix socket: %q %v", b[:n], err)
+	}
+}
+
+func TestSyslogTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("could not listen on loopback:", err)
+	}
+	defer ln.Close()
+	h := mustSyslog(t, "tcp", ln.Addr().String(), SyslogTimeout(50*time.Millisecond))
+	defer h.Close()
+	// the server accepts the connection but never reads
+	msg := []byte(strings.Repeat("x", 1<<16))
+	start := time.Now()
+	for i := 0; i < 1<<12; i++ {
+		if err = h.write(msg); err != nil {
+			break
+		}
+	}
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() || time.Since(start) > 5*time.Second {
+		t.Fatalf("syslog handler write did not time out: %v", err)
+	}
+}
+
+func mustSyslog(t *testing.T, network, addr string, opts ...SyslogOption) *SyslogHandler {
+	h, err := NewSyslogHandler(network, addr, opts...)
+	if err != nil {
+		t.Fatal("could not create syslog handler:", err)
+	}
+	return h
+}
@@ -3,4 +3,332 @@
 // license that can be found in the gosimple LICENSE file.
 // Author: jcdotter
 
+// The gosimple command provides tools for
+// the gosimple packages. Usage:
+//
+//	gosimple log [flags] [dir]
+//
+// The log command prints the records of the log files
+// in a log directory merged in timestamp order, filtered
+// and converted between the json, logfmt and delimited formats.
+// If a directory is not provided, the directory in
+// os.Getenv("GO_UTILS_LOG_PATH") or '../logs' is used
 package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jcdotter/gosimple/log"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command in 'args' and
+// returns the exit code of the command
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "usage: gosimple log [flags] [dir]")
+		return 2
+	}
+	switch args[0] {
+	case "log":
+		return logCmd(args[1:], stdout, stderr, nil)
+	}
+	fmt.Fprintf(stderr, "gosimple: unknown command %q\n", args[0])
+	return 2
+}
+
+// logOpts are the options of the log command
+type logOpts struct {
+	dir    string             // the log directory
+	follow bool               // if true, print records appended to the logs
+	poll   time.Duration      // the interval of polling the logs in follow mode
+	filter log.Filter         // the filter of the records printed
+	grep   *regexp.Regexp     // the pattern of the messages printed, if any
+	out    *log.WriterHandler // the handler printing records
+	w      io.Writer          // the output of the command
+	pretty bool               // if true, indent json records
+	scan   []log.ScanOption   // the options of the log scanners
+}
+
+// logCmd executes the log command, printing the
+// records of the log directory until 'stop' is closed
+// in follow mode, and returns the exit code of the command
+func logCmd(args []string, stdout, stderr io.Writer, stop <-chan struct{}) int {
+	fs := flag.NewFlagSet("log", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	follow := fs.Bool("f", false, "follow the logs, printing records as they are appended")
+	level := fs.String("level", "", "the minimum level of the records: TRACE, INFO, WARNING, ERROR or FATAL")
+	session := fs.String("session", "", "the session of the records")
+	host := fs.String("host", "", "the host of the records")
+	service := fs.String("service", "", "the service of the records")
+	since := fs.String("since", "", "the earliest time of the records, as a datetime or a duration before now such as 15m")
+	until := fs.String("until", "", "the latest time of the records, as a datetime or a duration before now")
+	grep := fs.String("grep", "", "a regular expression matching the messages of the records")
+	format := fs.String("format", "std", "the output format of the records: std, json or logfmt")
+	pretty := fs.Bool("pretty", false, "indent records in json format")
+	input := fs.String("input", "std", "the format of the lines of the logs: std, which also reads json lines, or logfmt")
+	elements := fs.String("elements", "", "the comma separated order of the elements of delimited records, such as level,datetime,source,message")
+	delim := fs.String("delim", " \t", "the delimiter between elements of delimited records")
+	timeFmt := fs.String("datetime", `2006-01-02 15:04:05.000`, "the datetime format of the records")
+	poll := fs.Duration("poll", 500*time.Millisecond, "the interval of polling the logs in follow mode")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	o := &logOpts{follow: *follow, poll: *poll, pretty: *pretty, w: stdout}
+	o.dir = fs.Arg(0)
+	if o.dir == "" {
+		if d, ok := os.LookupEnv("GO_UTILS_LOG_PATH"); ok {
+			o.dir = d
+		} else {
+			o.dir, _ = filepath.Abs("../logs")
+		}
+	}
+	var err error
+	if *level != "" {
+		l := log.LevelByName(*level)
+		if l.String() != strings.ToUpper(*level) {
+			err = fmt.Errorf("invalid level %q", *level)
+		}
+		o.filter.MinLevel = l
+	}
+	o.filter.Session, o.filter.Host, o.filter.Service = *session, *host, *service
+	if err == nil && *since != "" {
+		o.filter.Since, err = parseTime(*since, *timeFmt)
+	}
+	if err == nil && *until != "" {
+		o.filter.Until, err = parseTime(*until, *timeFmt)
+	}
+	if err == nil && *grep != "" {
+		o.grep, err = regexp.Compile(*grep)
+	}
+	var els []int
+	if err == nil && *elements != "" {
+		els, err = parseElements(*elements)
+	}
+	hopts := []log.HandlerOption{log.HandlerDelim(*delim), log.HandlerDateTimeFormat(*timeFmt)}
+	o.scan = []log.ScanOption{log.ScanDelim(*delim), log.ScanDateTimeFormat(*timeFmt), log.ScanFilter(o.filter)}
+	switch *input {
+	case "std":
+		o.scan = append(o.scan, log.ScanFormat(append([]int{log.LogStdFmt}, els...)...))
+	case "logfmt":
+		o.scan = append(o.scan, log.ScanFormat(log.LogLogfmt))
+	default:
+		err = fmt.Errorf("invalid input format %q", *input)
+	}
+	switch *format {
+	case "std":
+		if len(els) > 0 {
+			hopts = append(hopts, log.HandlerFormat(append([]int{log.LogStdFmt}, els...)...))
+		}
+	case "json":
+		hopts = append(hopts, log.HandlerFormat(log.LogJsonFmt, log.LogLevel, log.LogDateTime,
+			log.LogSession, log.LogHost, log.LogService, log.LogTraceID, log.LogSpanID, log.LogSource, log.LogMessage))
+	case "logfmt":
+		hopts = append(hopts, log.HandlerFormat(log.LogLogfmt, log.LogLevel, log.LogDateTime,
+			log.LogSession, log.LogHost, log.LogService, log.LogTraceID, log.LogSpanID, log.LogSource, log.LogMessage))
+	default:
+		err = fmt.Errorf("invalid format %q", *format)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "gosimple log:", err)
+		return 2
+	}
+	o.out = log.NewWriterHandler(stdout, hopts...)
+	offs, err := o.print()
+	if err != nil {
+		fmt.Fprintln(stderr, "gosimple log:", err)
+		return 1
+	}
+	if o.follow {
+		if err := o.tail(offs, stop); err != nil {
+			fmt.Fprintln(stderr, "gosimple log:", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// print prints the records of the log directory
+// and returns the offsets of the log files printed
+func (o *logOpts) print() (map[string]int64, error) {
+	d, err := log.ScanDir(o.dir, o.scan...)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	for d.Next() {
+		if err := o.write(d.Record()); err != nil {
+			return nil, err
+		}
+	}
+	return d.Offsets(), d.Err()
+}
+
+// write prints the record 'r' if
+// its message matches the pattern
+func (o *logOpts) write(r *log.Record) error {
+	if o.grep != nil && !o.grep.MatchString(r.Message) {
+		return nil
+	}
+	if !o.pretty {
+		return o.out.Handle(r)
+	}
+	b := new(bytes.Buffer)
+	log.NewWriterHandler(b, log.HandlerFormat(log.LogJsonFmt, log.LogLevel, log.LogDateTime,
+		log.LogSession, log.LogHost, log.LogService, log.LogTraceID, log.LogSpanID, log.LogSource, log.LogMessage)).Handle(r)
+	p := new(bytes.Buffer)
+	if err := json.Indent(p, bytes.TrimSpace(b.Bytes()), "", "  "); err != nil {
+		return err
+	}
+	p.WriteByte('\n')
+	_, err := o.w.Write(p.Bytes())
+	return err
+}
+
+// logFile is a log file followed by the tail of
+// the log directory, identified by its file info
+// so a log file renamed by rotation keeps its offset
+type logFile struct {
+	info os.FileInfo // the file info of the log file
+	off  int64       // the offset of the records printed
+}
+
+// tail prints the records appended to the log files
+// of the log directory since the offsets 'offs' printed,
+// including new log files, polling the directory
+// until 'stop' is closed
+func (o *logOpts) tail(offs map[string]int64, stop <-chan struct{}) error {
+	var files []*logFile
+	for n, off := range offs {
+		if info, err := os.Stat(n); err == nil && strings.HasSuffix(n, ".log") {
+			files = append(files, &logFile{info, off})
+		}
+	}
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(o.poll):
+		}
+		names, _ := filepath.Glob(filepath.Join(o.dir, "*.log"))
+		sort.Strings(names)
+		var tailed []*logFile
+		for _, n := range names {
+			f, err := o.tailFile(n, files)
+			if err != nil {
+				return err
+			}
+			if f != nil {
+				tailed = append(tailed, f)
+			}
+		}
+		files = tailed
+	}
+}
+
+// tailFile prints the complete records appended to the
+// log file 'name' since its offset in the log files 'files'
+// followed by the previous poll, matched by file identity,
+// and returns the log file with its new offset
+func (o *logOpts) tailFile(name string, files []*logFile) (*logFile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil
+	}
+	lf := &logFile{info: info}
+	for _, p := range files {
+		if os.SameFile(p.info, info) {
+			lf.off = p.off
+			break
+		}
+	}
+	if info.Size() < lf.off { // the file was truncated
+		lf.off = 0
+	}
+	if info.Size() == lf.off {
+		return lf, nil
+	}
+	if _, err := f.Seek(lf.off, io.SeekStart); err != nil {
+		return nil, err
+	}
+	lr := &lineReader{r: bufio.NewReader(io.LimitReader(f, info.Size()-lf.off))}
+	s := log.Scan(lr, o.scan...)
+	for s.Next() {
+		if err := o.write(s.Record()); err != nil {
+			return nil, err
+		}
+	}
+	lf.off += lr.n
+	return lf, s.Err()
+}
+
+// lineReader reads the complete lines of a reader,
+// counting the bytes read, and ends before a line
+// which is not yet complete
+type lineReader struct {
+	r  *bufio.Reader // the reader of the lines
+	ln []byte        // the unread bytes of the current line
+	n  int64         // the bytes of the lines read
+}
+
+// Read reads the complete lines of the reader into 'p'
+func (l *lineReader) Read(p []byte) (int, error) {
+	if len(l.ln) == 0 {
+		ln, err := l.r.ReadBytes('\n')
+		if err != nil {
+			return 0, err
+		}
+		l.ln = ln
+		l.n += int64(len(ln))
+	}
+	n := copy(p, l.ln)
+	l.ln = l.ln[n:]
+	return n, nil
+}
+
+// parseElements parses the comma separated
+// element names in 's' as log format elements
+func parseElements(s string) ([]int, error) {
+	var els []int
+	for _, n := range strings.Split(s, ",") {
+		e, ok := log.ElementByName(strings.TrimSpace(n))
+		if !ok {
+			return nil, fmt.Errorf("invalid element %q", n)
+		}
+		els = append(els, e)
+	}
+	return els, nil
+}
+
+// parseTime parses 's' as a duration before now,
+// as a datetime in the format 'timeFmt' or as RFC 3339
+func parseTime(s, timeFmt string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, f := range []string{timeFmt, time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(f, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
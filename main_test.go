@@ -0,0 +1,179 @@
+// Copyright 2022 escend llc. All rights reserved.
+// Use of this source code is governed by a
+// license that can be found in the gosimple LICENSE file.
+// Author: jcdotter
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jcdotter/gosimple/log"
+)
+
+var logLines = "INFO \t2022-01-01 10:00:00.000 \ts1 \ta.go:1 \tstarted worker\n" +
+	"ERROR \t2022-01-01 10:00:02.000 \ts1 \ta.go:2 \tworker failed \tcode=7\n"
+
+func TestLogCmd(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "s1.log"), []byte(logLines), os.ModePerm)
+	os.WriteFile(filepath.Join(dir, "s2.log"), []byte(
+		`{"level":"WARNING","datetime":"2022-01-01 10:00:01.000","session":"s2","host":"h2","message":"worker slow"}`+"\n"), os.ModePerm)
+	for _, tc := range []struct {
+		args []string
+		exp  string
+	}{
+		{[]string{"-session", "s1", dir}, logLines},
+		{[]string{"-level", "warning", "-grep", "fail", dir}, strings.Split(logLines, "\n")[1] + "\n"},
+		{[]string{"-host", "h2", "-format", "json", dir},
+			`{"datetime":"2022-01-01 10:00:01.000","host":"h2","level":"WARNING","message":"worker slow","session":"s2"}` + "\n"},
+		{[]string{"-level", "error", "-pretty", dir},
+			"{\n  \"code\": \"7\",\n  \"datetime\": \"2022-01-01 10:00:02.000\",\n  \"level\": \"ERROR\",\n" +
+				"  \"message\": \"worker failed\",\n  \"session\": \"s1\",\n  \"source\": \"a.go:2\"\n}\n"},
+		{[]string{"-until", "2022-01-01 10:00:01", dir}, strings.Split(logLines, "\n")[0] + "\n"},
+		{[]string{"-level", "error", "-format", "logfmt", dir},
+			`level=ERROR datetime="2022-01-01 10:00:02.000" session=s1 source=a.go:2 message="worker failed" code=7` + "\n"},
+	} {
+		out, errs := new(bytes.Buffer), new(bytes.Buffer)
+		if c := run(append([]string{"log"}, tc.args...), out, errs); c != 0 {
+			t.Fatalf("log command %v exited with %d: %s", tc.args, c, errs)
+		}
+		if out.String() != tc.exp {
+			t.Fatalf("log command %v printed:\n%s\nexpected:\n%s", tc.args, out, tc.exp)
+		}
+	}
+	if c := run([]string{"log", "-level", "loud", dir}, new(bytes.Buffer), new(bytes.Buffer)); c != 2 {
+		t.Fatal("log command did not reject invalid level")
+	}
+	if c := run([]string{"log", "-elements", "level,color", dir}, new(bytes.Buffer), new(bytes.Buffer)); c != 2 {
+		t.Fatal("log command did not reject invalid element")
+	}
+}
+
+func TestLogCmdElements(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "s1.log"), []byte(
+		"2022-01-01 10:00:00.000|INFO|started worker\n2022-01-01 10:00:02.000|ERROR|worker failed|code=7\n"), os.ModePerm)
+	out, errs := new(bytes.Buffer), new(bytes.Buffer)
+	args := []string{"log", "-elements", "datetime,level,message", "-delim", "|", "-level", "error", "-format", "logfmt", dir}
+	if c := run(args, out, errs); c != 0 {
+		t.Fatalf("log command exited with %d: %s", c, errs)
+	}
+	exp := `level=ERROR datetime="2022-01-01 10:00:02.000" message="worker failed" code=7` + "\n"
+	if out.String() != exp {
+		t.Fatalf("log command printed:\n%s\nexpected:\n%s", out, exp)
+	}
+}
+
+// syncBuffer is a buffer safe for concurrent use
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+func TestLogCmdFollow(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "s1.log")
+	os.WriteFile(name, []byte(strings.Split(logLines, "\n")[0]+"\n"), os.ModePerm)
+	out, stop, done := &syncBuffer{}, make(chan struct{}), make(chan int)
+	go func() { done <- logCmd([]string{"-f", "-poll", "10ms", dir}, out, os.Stderr, stop) }()
+	for i := 0; i < 100 && out.String() == ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	f, _ := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, os.ModePerm)
+	f.WriteString(strings.Split(logLines, "\n")[1][:10])
+	time.Sleep(50 * time.Millisecond)
+	f.WriteString(strings.Split(logLines, "\n")[1][10:] + "\n")
+	f.Close()
+	exp := logLines
+	for i := 0; i < 100 && out.String() != exp; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(stop)
+	if c := <-done; c != 0 || out.String() != exp {
+		t.Fatalf("log command did not follow appended records: %q", out.String())
+	}
+}
+
+func TestLogCmdFollowRotation(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "s1.log")
+	lines := strings.SplitAfter(logLines, "\n")
+	os.WriteFile(name, []byte(lines[0]), os.ModePerm)
+	out, stop, done := &syncBuffer{}, make(chan struct{}), make(chan int)
+	go func() { done <- logCmd([]string{"-f", "-poll", "10ms", dir}, out, os.Stderr, stop) }()
+	for i := 0; i < 100 && out.String() == ""; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	// append a record and rotate the file before the next poll
+	f, _ := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, os.ModePerm)
+	f.WriteString(lines[1])
+	f.Close()
+	os.Rename(name, filepath.Join(dir, "s1-20220101T100002.log"))
+	next := "INFO \t2022-01-01 10:00:03.000 \ts1 \ta.go:3 \tworker restarted\n"
+	os.WriteFile(name, []byte(next), os.ModePerm)
+	exp := logLines + next
+	for i := 0; i < 100 && out.String() != exp; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(stop)
+	if c := <-done; c != 0 || out.String() != exp {
+		t.Fatalf("log command did not follow rotated log: %q", out.String())
+	}
+}
+
+func TestLogCmdTailOffsets(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "s1.log")
+	lines := strings.SplitAfter(logLines, "\n")
+	next := "INFO \t2022-01-01 10:00:03.000 \ts1 \ta.go:3 \tworker restarted\n"
+	os.WriteFile(name, []byte(lines[0]), os.ModePerm)
+	timeFmt, out := "2006-01-02 15:04:05.000", &syncBuffer{}
+	o := &logOpts{dir: dir, poll: 10 * time.Millisecond, w: out,
+		out:  log.NewWriterHandler(out, log.HandlerDelim(" \t"), log.HandlerDateTimeFormat(timeFmt)),
+		scan: []log.ScanOption{log.ScanDelim(" \t"), log.ScanDateTimeFormat(timeFmt)},
+	}
+	offs, err := o.print()
+	if err != nil {
+		t.Fatal("could not print logs:", err)
+	}
+	// records appended after printing and a partial line
+	// are printed by the tail once the line is complete
+	f, _ := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, os.ModePerm)
+	f.WriteString(lines[1] + next[:10])
+	stop, done := make(chan struct{}), make(chan error)
+	go func() { done <- o.tail(offs, stop) }()
+	for i := 0; i < 100 && out.String() != lines[0]+lines[1]; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	f.WriteString(next[10:])
+	f.Close()
+	for i := 0; i < 100 && out.String() != logLines+next; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(stop)
+	if err := <-done; err != nil || out.String() != logLines+next {
+		t.Fatalf("log command did not tail from the offsets printed: %q %v", out.String(), err)
+	}
+}